@@ -9,8 +9,10 @@ require (
 	github.com/gorilla/mux v1.7.4
 	github.com/mattermost/mattermost-server/v5 v5.26.2
 	github.com/pkg/errors v0.9.1
+	github.com/stretchr/testify v1.6.1
 	github.com/thoas/go-funk v0.7.0
 	go.uber.org/atomic v1.6.0
+	gopkg.in/yaml.v2 v2.3.0
 )
 
 // To access the TomTucka/go-circleci v2 repo fork
@@ -0,0 +1,355 @@
+package command
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/config"
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/serializer"
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/service"
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/store"
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/util"
+)
+
+var commandAdminMapUser = &command{
+	Execute: executeAdminMapUser,
+	AutocompleteData: &model.AutocompleteData{
+		Trigger:  "map-user",
+		HelpText: "Map a GitHub or Bitbucket login to a Mattermost user so that they are mentioned in notifications",
+		RoleID:   model.SYSTEM_ADMIN_ROLE_ID,
+		Arguments: []*model.AutocompleteArg{
+			{
+				HelpText: "Type of the VCS of the login",
+				Type:     model.AutocompleteArgTypeStaticList,
+				Required: true,
+				Data: &model.AutocompleteStaticListArg{
+					PossibleArguments: []model.AutocompleteListItem{
+						{
+							Item:     serializer.VCSTypeGithub,
+							HelpText: "GitHub or GitHub Enterprise login",
+						},
+						{
+							Item:     serializer.VCSTypeBitbucket,
+							HelpText: "Bitbucket login",
+						},
+					},
+				},
+			},
+			{
+				HelpText: "Login of the user on the VCS",
+				Type:     model.AutocompleteArgTypeText,
+				Required: true,
+				Data: &model.AutocompleteTextArg{
+					Hint:    "VCS login",
+					Pattern: ".+",
+				},
+			},
+			{
+				HelpText: "Mattermost username",
+				Type:     model.AutocompleteArgTypeText,
+				Required: true,
+				Data: &model.AutocompleteTextArg{
+					Hint:    "@username",
+					Pattern: ".+",
+				},
+			},
+		},
+	},
+}
+
+var commandAdminUnmapUser = &command{
+	Execute: executeAdminUnmapUser,
+	AutocompleteData: &model.AutocompleteData{
+		Trigger:  "unmap-user",
+		HelpText: "Remove the mapping of a GitHub or Bitbucket login",
+		RoleID:   model.SYSTEM_ADMIN_ROLE_ID,
+		Arguments: []*model.AutocompleteArg{
+			{
+				HelpText: "Type of the VCS of the login",
+				Type:     model.AutocompleteArgTypeStaticList,
+				Required: true,
+				Data: &model.AutocompleteStaticListArg{
+					PossibleArguments: []model.AutocompleteListItem{
+						{
+							Item:     serializer.VCSTypeGithub,
+							HelpText: "GitHub or GitHub Enterprise login",
+						},
+						{
+							Item:     serializer.VCSTypeBitbucket,
+							HelpText: "Bitbucket login",
+						},
+					},
+				},
+			},
+			{
+				HelpText: "Login of the user on the VCS",
+				Type:     model.AutocompleteArgTypeText,
+				Required: true,
+				Data: &model.AutocompleteTextArg{
+					Hint:    "VCS login",
+					Pattern: ".+",
+				},
+			},
+		},
+	},
+}
+
+var commandAdminListUserMappings = &command{
+	Execute: executeAdminListUserMappings,
+	AutocompleteData: &model.AutocompleteData{
+		Trigger:  "list-user-mappings",
+		HelpText: "List the GitHub and Bitbucket logins mapped to Mattermost users",
+		RoleID:   model.SYSTEM_ADMIN_ROLE_ID,
+	},
+}
+
+var commandAdminReencryptTokens = &command{
+	Execute: executeAdminReencryptTokens,
+	AutocompleteData: &model.AutocompleteData{
+		Trigger:  "reencrypt-tokens",
+		HelpText: "Encrypt all the stored auth tokens with the current encryption key after rotating it",
+		RoleID:   model.SYSTEM_ADMIN_ROLE_ID,
+	},
+}
+
+var commandAdminSubscriptions = &command{
+	Execute: executeAdminSubscriptions,
+	AutocompleteData: &model.AutocompleteData{
+		Trigger:  "subscriptions",
+		HelpText: "List the subscriptions of all the channels",
+		RoleID:   model.SYSTEM_ADMIN_ROLE_ID,
+		Arguments: []*model.AutocompleteArg{
+			{
+				HelpText: "Remove the subscriptions of archived and deleted channels",
+				Type:     model.AutocompleteArgTypeStaticList,
+				Required: false,
+				Data: &model.AutocompleteStaticListArg{
+					PossibleArguments: []model.AutocompleteListItem{
+						{
+							Item:     "prune",
+							HelpText: "Remove the subscriptions of archived and deleted channels",
+						},
+					},
+				},
+			},
+		},
+	},
+}
+
+var commandAdmin = &model.AutocompleteData{
+	Trigger:  "admin",
+	HelpText: "Administrative commands. Only available to system admins.",
+	RoleID:   model.SYSTEM_ADMIN_ROLE_ID,
+	SubCommands: []*model.AutocompleteData{
+		commandAdminMapUser.AutocompleteData,
+		commandAdminUnmapUser.AutocompleteData,
+		commandAdminListUserMappings.AutocompleteData,
+		commandAdminReencryptTokens.AutocompleteData,
+		commandAdminSubscriptions.AutocompleteData,
+	},
+}
+
+func executeAdminMapUser(ctx *model.CommandArgs, args ...string) (*model.CommandResponse, *model.AppError) {
+	if !isSystemAdmin(ctx.UserId) {
+		return util.SendEphemeralCommandResponse("Only system admins can manage user mappings.")
+	}
+
+	if len(args) != 3 {
+		return util.SendEphemeralCommandResponse("Incorrect syntax. Use this command as `/circleci admin map-user <github | bitbucket> <vcs login> <@username>`")
+	}
+
+	vcsType, vcsLogin, username := serializer.NormalizeVCSType(args[0]), args[1], strings.TrimPrefix(args[2], "@")
+	if vcsType != serializer.VCSTypeGithub && vcsType != serializer.VCSTypeBitbucket {
+		return util.SendEphemeralCommandResponse(fmt.Sprintf("Invalid VCS type `%s`. Please specify one of `%s` or `%s`.", args[0], serializer.VCSTypeGithub, serializer.VCSTypeBitbucket))
+	}
+
+	user, appErr := config.Mattermost.GetUserByUsername(username)
+	if appErr != nil {
+		return util.SendEphemeralCommandResponse(fmt.Sprintf("Unable to find the Mattermost user `%s`.", username))
+	}
+
+	if err := store.SaveUserMapping(&serializer.UserMapping{VCSType: vcsType, VCSLogin: vcsLogin, UserID: user.Id}); err != nil {
+		return util.SendEphemeralCommandResponse("Failed to save the user mapping. Please try again later. If the problem persists, contact your system administrator.")
+	}
+
+	return util.SendEphemeralCommandResponse(fmt.Sprintf("Successfully mapped the %s login `%s` to @%s.", vcsType, vcsLogin, user.Username))
+}
+
+func executeAdminUnmapUser(ctx *model.CommandArgs, args ...string) (*model.CommandResponse, *model.AppError) {
+	if !isSystemAdmin(ctx.UserId) {
+		return util.SendEphemeralCommandResponse("Only system admins can manage user mappings.")
+	}
+
+	if len(args) != 2 {
+		return util.SendEphemeralCommandResponse("Incorrect syntax. Use this command as `/circleci admin unmap-user <github | bitbucket> <vcs login>`")
+	}
+
+	vcsType, vcsLogin := serializer.NormalizeVCSType(args[0]), args[1]
+
+	mapping, err := store.GetUserMapping(vcsType, vcsLogin)
+	if err != nil {
+		return util.SendEphemeralCommandResponse("Failed to get the user mapping. Please try again later. If the problem persists, contact your system administrator.")
+	}
+
+	if mapping == nil {
+		return util.SendEphemeralCommandResponse(fmt.Sprintf("The %s login `%s` is not mapped to any Mattermost user.", vcsType, vcsLogin))
+	}
+
+	if err := store.DeleteUserMapping(vcsType, vcsLogin); err != nil {
+		return util.SendEphemeralCommandResponse("Failed to remove the user mapping. Please try again later. If the problem persists, contact your system administrator.")
+	}
+
+	return util.SendEphemeralCommandResponse(fmt.Sprintf("Successfully removed the mapping of the %s login `%s`.", vcsType, vcsLogin))
+}
+
+func executeAdminListUserMappings(ctx *model.CommandArgs, args ...string) (*model.CommandResponse, *model.AppError) {
+	if !isSystemAdmin(ctx.UserId) {
+		return util.SendEphemeralCommandResponse("Only system admins can manage user mappings.")
+	}
+
+	mappings, err := store.ListUserMappings()
+	if err != nil {
+		return util.SendEphemeralCommandResponse("Failed to fetch the user mappings. Please try again later. If the problem persists, contact your system administrator.")
+	}
+
+	if len(mappings) == 0 {
+		return util.SendEphemeralCommandResponse("No users are mapped yet. Users are mapped automatically when they run `/circleci connect`, or manually using `/circleci admin map-user`.")
+	}
+
+	message := "| VCS | VCS Login | Mattermost User |\n| :-- | :-- | :-- |\n"
+	for _, mapping := range mappings {
+		username := mapping.UserID
+		if user, appErr := config.Mattermost.GetUser(mapping.UserID); appErr == nil {
+			username = "@" + user.Username
+		}
+		message += fmt.Sprintf("| %s | %s | %s |\n", mapping.VCSType, mapping.VCSLogin, username)
+	}
+
+	return util.SendEphemeralCommandResponse(message)
+}
+
+func executeAdminReencryptTokens(ctx *model.CommandArgs, args ...string) (*model.CommandResponse, *model.AppError) {
+	if !isSystemAdmin(ctx.UserId) {
+		return util.SendEphemeralCommandResponse("Only system admins can re-encrypt the auth tokens.")
+	}
+
+	migrated, failedUserIDs, err := service.ReencryptAuthTokens()
+	if err != nil {
+		return util.SendEphemeralCommandResponse(fmt.Sprintf("Failed to re-encrypt the auth tokens. %d tokens were re-encrypted before the failure. Error: %s", migrated, err.Error()))
+	}
+
+	message := fmt.Sprintf("Successfully re-encrypted %d auth tokens with the current encryption key.", migrated)
+	if len(failedUserIDs) > 0 {
+		message += fmt.Sprintf(" %d tokens were encrypted with an unknown key and could not be migrated. The affected users have been asked to connect their account again.", len(failedUserIDs))
+	} else {
+		message += " The previous encryption key is not needed anymore and can be removed from the plugin settings."
+	}
+
+	return util.SendEphemeralCommandResponse(message)
+}
+
+func executeAdminSubscriptions(ctx *model.CommandArgs, args ...string) (*model.CommandResponse, *model.AppError) {
+	if !isSystemAdmin(ctx.UserId) {
+		return util.SendEphemeralCommandResponse("Only system admins can manage the subscriptions of all the channels.")
+	}
+
+	prune := len(args) == 1 && args[0] == "prune"
+	if len(args) > 0 && !prune {
+		return util.SendEphemeralCommandResponse("Incorrect syntax. Use this command as `/circleci admin subscriptions [prune]`")
+	}
+
+	subscriptions, err := service.ListAllSubscriptions()
+	if err != nil {
+		return util.SendEphemeralCommandResponse("Failed to fetch the subscriptions. Please try again later. If the problem persists, contact your system administrator.")
+	}
+
+	channelIDs := make([]string, 0, len(subscriptions.ByChannelID))
+	for channelID, channelSubscriptions := range subscriptions.ByChannelID {
+		if len(channelSubscriptions) > 0 {
+			channelIDs = append(channelIDs, channelID)
+		}
+	}
+	sort.Strings(channelIDs)
+
+	if len(channelIDs) == 0 {
+		return util.SendEphemeralCommandResponse("No channel is subscribed to any repository.")
+	}
+
+	var staleChannelIDs []string
+	failedChannels := 0
+	teamNames := map[string]string{}
+	message := "| Team | Channel | Repository | Filters | Channel Status |\n| :-- | :-- | :-- | :-- | :-- |\n"
+	for _, channelID := range channelIDs {
+		teamName, channelName, status := "", channelID, "active"
+
+		channel, appErr := config.Mattermost.GetChannel(channelID)
+		switch {
+		case appErr != nil && appErr.StatusCode == http.StatusNotFound:
+			status = "deleted"
+			staleChannelIDs = append(staleChannelIDs, channelID)
+		case appErr != nil:
+			// The channel may still exist, so its subscriptions are never pruned
+			config.Mattermost.LogError("Failed to get the subscribed channel.", "ChannelID", channelID, "Error", appErr.Error())
+			status = "unknown (failed to get the channel)"
+			failedChannels++
+		case channel.DeleteAt != 0:
+			status = "archived"
+			staleChannelIDs = append(staleChannelIDs, channelID)
+			fallthrough
+		default:
+			channelName = "~" + channel.Name
+			teamName = getTeamName(channel.TeamId, teamNames)
+		}
+
+		for _, s := range subscriptions.List(channelID) {
+			message += fmt.Sprintf("| %s | %s | %s/%s/%s | %s | %s |\n", teamName, channelName, s.VCSType, s.OrgName, s.RepoName, s.Filters.String(), status)
+		}
+	}
+
+	failureMessage := ""
+	if failedChannels > 0 {
+		failureMessage = fmt.Sprintf("\n%d channels could not be fetched. Their subscriptions are not pruned.", failedChannels)
+	}
+
+	if !prune {
+		message += failureMessage
+		if len(staleChannelIDs) > 0 {
+			message += fmt.Sprintf("\n%d channels are archived or deleted. Use `/circleci admin subscriptions prune` to remove their subscriptions.", len(staleChannelIDs))
+		}
+
+		return util.SendEphemeralCommandResponse(message)
+	}
+
+	if len(staleChannelIDs) == 0 {
+		return util.SendEphemeralCommandResponse("No subscriptions to prune. None of the subscribed channels are archived or deleted." + failureMessage)
+	}
+
+	if err := service.RemoveChannelSubscriptions(staleChannelIDs); err != nil {
+		return util.SendEphemeralCommandResponse("Failed to prune the subscriptions. Please try again later. If the problem persists, contact your system administrator.")
+	}
+
+	return util.SendEphemeralCommandResponse(fmt.Sprintf("Successfully removed the subscriptions of %d archived or deleted channels.", len(staleChannelIDs)) + failureMessage)
+}
+
+// getTeamName returns the display name of the team, caching it for the other channels of the team
+func getTeamName(teamID string, cache map[string]string) string {
+	if teamID == "" {
+		return ""
+	}
+
+	if name, ok := cache[teamID]; ok {
+		return name
+	}
+
+	name := teamID
+	if team, appErr := config.Mattermost.GetTeam(teamID); appErr == nil {
+		name = team.DisplayName
+	}
+	cache[teamID] = name
+
+	return name
+}
@@ -0,0 +1,67 @@
+package command
+
+import (
+	"context"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/config"
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/service"
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/util"
+)
+
+// commandResponseTimeout is how long a command fetching data from CircleCI waits before replying
+// that its result will be posted later, so that the slash command doesn't time out in the client.
+const commandResponseTimeout = 2 * time.Second
+
+type commandResult struct {
+	response *model.CommandResponse
+	appErr   *model.AppError
+}
+
+// executeWithDeadline runs a command handler making requests to CircleCI with a context bounded by service.RequestDeadline.
+// If the handler takes longer than commandResponseTimeout, the user is told to wait and the ephemeral response of the
+// handler is sent as an ephemeral post once it finishes.
+func executeWithDeadline(args *model.CommandArgs, handler func(ctx context.Context) (*model.CommandResponse, *model.AppError)) (*model.CommandResponse, *model.AppError) {
+	resultChan := make(chan commandResult, 1)
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), service.RequestDeadline)
+		defer cancel()
+
+		response, appErr := handler(ctx)
+		resultChan <- commandResult{response: response, appErr: appErr}
+	}()
+
+	select {
+	case result := <-resultChan:
+		return result.response, result.appErr
+	case <-time.After(commandResponseTimeout):
+	}
+
+	go func() {
+		result := <-resultChan
+
+		message := ""
+		switch {
+		case result.appErr != nil:
+			message = result.appErr.Error()
+		case result.response != nil:
+			message = result.response.Text
+		}
+
+		if message == "" {
+			return
+		}
+
+		config.Mattermost.SendEphemeralPost(args.UserId, &model.Post{
+			UserId:    config.BotUserID,
+			ChannelId: args.ChannelId,
+			RootId:    args.RootId,
+			Message:   message,
+		})
+	}()
+
+	return util.SendEphemeralCommandResponse("Fetching the data from CircleCI. The result will be posted here once it's ready.")
+}
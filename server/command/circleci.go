@@ -2,13 +2,14 @@ package command
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
 	circleci2 "github.com/TomTucka/go-circleci/circleci"
-	"github.com/antihax/optional"
 	"github.com/dustin/go-humanize"
 	"github.com/mattermost/mattermost-server/v5/model"
 	"github.com/thoas/go-funk"
@@ -22,8 +23,6 @@ import (
 
 const (
 	invalidCommand = "Invalid command parameters. Please check the autocomplete for more information."
-	HeadTypeBranch = "branch"
-	HeadTypeTag    = "tag"
 )
 
 type command struct {
@@ -31,22 +30,53 @@ type command struct {
 	AutocompleteData *model.AutocompleteData
 }
 
+// vcsAliasAutocompleteArg suggests the default and the custom VCS aliases
+var vcsAliasAutocompleteArg = &model.AutocompleteArg{
+	HelpText: "VCS Alias",
+	Type:     model.AutocompleteArgTypeDynamicList,
+	Required: true,
+	Data: &model.AutocompleteDynamicListArg{
+		FetchURL: config.URLAPIBase + config.PathAutocompleteVCS,
+	},
+}
+
+// The project arguments are optional, as the commands fall back to the default project of the channel
+var (
+	projectVCSAutocompleteArg = &model.AutocompleteArg{
+		HelpText: "VCS Alias, or the project as a slug such as `gh/org/repo` or as a URL. Can be omitted if the default project of the channel is set.",
+		Type:     model.AutocompleteArgTypeDynamicList,
+		Required: false,
+		Data: &model.AutocompleteDynamicListArg{
+			FetchURL: config.URLAPIBase + config.PathAutocompleteVCS,
+		},
+	}
+
+	projectOrgAutocompleteArg = &model.AutocompleteArg{
+		HelpText: "Org name on the VCS. For example org name for `github.com/foo/bar` would be `foo`.",
+		Type:     model.AutocompleteArgTypeText,
+		Required: false,
+		Data: &model.AutocompleteTextArg{
+			Hint:    "Org Name",
+			Pattern: "._+",
+		},
+	}
+
+	projectRepoAutocompleteArg = &model.AutocompleteArg{
+		HelpText: "Repository name on the VCS. For example repository name for `github.com/foo/bar` would be `bar`.",
+		Type:     model.AutocompleteArgTypeText,
+		Required: false,
+		Data: &model.AutocompleteTextArg{
+			Hint:    "Repository Name",
+			Pattern: "._+",
+		},
+	}
+)
+
 var commandConnect = &command{
 	Execute: executeConnect,
 	AutocompleteData: &model.AutocompleteData{
-		Trigger:  "connect",
-		HelpText: "Connect with CircleCI account",
-		Arguments: []*model.AutocompleteArg{
-			{
-				HelpText: "CircleCI Auth Token",
-				Type:     model.AutocompleteArgTypeText,
-				Required: true,
-				Data: &model.AutocompleteTextArg{
-					Hint:    "CircleCI Auth Token",
-					Pattern: ".+",
-				},
-			},
-		},
+		Trigger:     "connect",
+		HelpText:    "Connect with CircleCI account",
 		SubCommands: nil,
 	},
 }
@@ -59,91 +89,139 @@ var commandDisconnect = &command{
 	},
 }
 
-var commandSubscribe = &command{
-	Execute: executeSubscribe,
+var commandMe = &command{
+	Execute: executeMe,
 	AutocompleteData: &model.AutocompleteData{
-		Trigger:  "subscribe",
-		HelpText: "Subscribe to specified CircleCI notifications in the current channel",
+		Trigger:  "me",
+		HelpText: "Show the connected CircleCI account and check if its auth token still works",
+	},
+}
+
+var commandNotifyMe = &command{
+	Execute: executeNotifyMe,
+	AutocompleteData: &model.AutocompleteData{
+		Trigger:  "notify-me",
+		HelpText: "Get a direct message when a workflow you triggered finishes",
 		Arguments: []*model.AutocompleteArg{
 			{
-				HelpText: "VCS Alias",
+				HelpText: "Results to notify",
 				Type:     model.AutocompleteArgTypeStaticList,
 				Required: true,
 				Data: &model.AutocompleteStaticListArg{
 					PossibleArguments: []model.AutocompleteListItem{
 						{
-							Item:     "github",
-							HelpText: "Use a Github repository",
+							Item:     "on",
+							HelpText: "Notify for all results",
 						},
 						{
-							Item:     "bitbucket",
-							HelpText: "Use a Bitbucket repository",
+							Item:     serializer.NotifyMeFailures,
+							HelpText: "Notify for failures only",
+						},
+						{
+							Item:     serializer.NotifyMeOff,
+							HelpText: "Turn off personal notifications",
 						},
 					},
 				},
 			},
+		},
+		SubCommands: nil,
+	},
+}
+
+var commandSubscribe = &command{
+	Execute: executeSubscribe,
+	AutocompleteData: &model.AutocompleteData{
+		Trigger:  "subscribe",
+		HelpText: "Subscribe to specified CircleCI notifications in the current channel",
+		Arguments: []*model.AutocompleteArg{
+			projectVCSAutocompleteArg,
+			projectOrgAutocompleteArg,
+			projectRepoAutocompleteArg,
 			{
-				HelpText: "Org name on the VCS. For example org name for `github.com/foo/bar` would be `foo`.",
+				Name:     "branch",
+				HelpText: "Only notify for branches matching the glob pattern. For example `main` or `release/*`. `*` doesn't match `/`. Can be comma-separated.",
 				Type:     model.AutocompleteArgTypeText,
-				Required: true,
+				Required: false,
 				Data: &model.AutocompleteTextArg{
-					Hint:    "Org Name",
-					Pattern: "._+",
+					Hint:    "Branch pattern",
+					Pattern: ".+",
 				},
 			},
 			{
-				HelpText: "Repository name on the VCS. For example repository name for `github.com/foo/bar` would be `bar`.",
+				Name:     "job",
+				HelpText: "Only notify for jobs matching the glob pattern. For example `deploy-*`. Can be comma-separated.",
 				Type:     model.AutocompleteArgTypeText,
-				Required: true,
+				Required: false,
 				Data: &model.AutocompleteTextArg{
-					Hint:    "Repository Name",
-					Pattern: "._+",
+					Hint:    "Job name pattern",
+					Pattern: ".+",
 				},
 			},
-		},
-		SubCommands: nil,
-	},
-}
-
-var commandUnsubscribe = &command{
-	Execute: executeUnsubscribe,
-	AutocompleteData: &model.AutocompleteData{
-		Trigger:  "unsubscribe",
-		HelpText: "Unsubscribe to specified CircleCI notifications in the current channel",
-		Arguments: []*model.AutocompleteArg{
 			{
-				HelpText: "VCS Alias",
+				Name:     "status",
+				HelpText: "Only notify for the specified job statuses. Can be comma-separated. Defaults to `success,failure`.",
 				Type:     model.AutocompleteArgTypeStaticList,
-				Required: true,
+				Required: false,
 				Data: &model.AutocompleteStaticListArg{
 					PossibleArguments: []model.AutocompleteListItem{
 						{
-							Item:     "github",
-							HelpText: "Use a Github repository",
+							Item:     serializer.StatusFailure,
+							HelpText: "Notify for failed jobs",
+						},
+						{
+							Item:     serializer.StatusSuccess,
+							HelpText: "Notify for successful jobs",
+						},
+						{
+							Item:     serializer.StatusCanceled,
+							HelpText: "Notify for canceled jobs",
+						},
+						{
+							Item:     serializer.StatusOnHold,
+							HelpText: "Notify for jobs on hold which need an approval",
 						},
 						{
-							Item:     "bitbucket",
-							HelpText: "Use a Bitbucket repository",
+							Item:     serializer.StatusRunning,
+							HelpText: "Notify for jobs which have started running",
+						},
+						{
+							Item:     serializer.StatusUnauthorized,
+							HelpText: "Notify for jobs which were not authorized to run",
 						},
 					},
 				},
 			},
 			{
-				HelpText: "Org name on the VCS. For example org name for `github.com/foo/bar` would be `foo`.",
-				Type:     model.AutocompleteArgTypeText,
-				Required: true,
-				Data: &model.AutocompleteTextArg{
-					Hint:    "Org Name",
-					Pattern: "._+",
+				Name:     "tags-only",
+				HelpText: "Only notify for jobs running against a tag",
+				Type:     model.AutocompleteArgTypeStaticList,
+				Required: false,
+				Data: &model.AutocompleteStaticListArg{
+					PossibleArguments: []model.AutocompleteListItem{
+						{
+							Item: "true",
+						},
+						{
+							Item: "false",
+						},
+					},
 				},
 			},
 			{
-				HelpText: "Repository name on the VCS. For example repository name for `github.com/foo/bar` would be `bar`.",
-				Type:     model.AutocompleteArgTypeText,
-				Required: true,
-				Data: &model.AutocompleteTextArg{
-					Hint:    "Repository Name",
-					Pattern: "._+",
+				Name:     "group-by-workflow",
+				HelpText: "Show all the jobs of a workflow in a single post which is updated as the jobs finish",
+				Type:     model.AutocompleteArgTypeStaticList,
+				Required: false,
+				Data: &model.AutocompleteStaticListArg{
+					PossibleArguments: []model.AutocompleteListItem{
+						{
+							Item: "true",
+						},
+						{
+							Item: "false",
+						},
+					},
 				},
 			},
 		},
@@ -151,6 +229,20 @@ var commandUnsubscribe = &command{
 	},
 }
 
+var commandUnsubscribe = &command{
+	Execute: executeUnsubscribe,
+	AutocompleteData: &model.AutocompleteData{
+		Trigger:  "unsubscribe",
+		HelpText: "Unsubscribe to specified CircleCI notifications in the current channel",
+		Arguments: []*model.AutocompleteArg{
+			projectVCSAutocompleteArg,
+			projectOrgAutocompleteArg,
+			projectRepoAutocompleteArg,
+		},
+		SubCommands: nil,
+	},
+}
+
 var commandListSubscriptions = &command{
 	Execute: executeListSubscriptions,
 	AutocompleteData: &model.AutocompleteData{
@@ -161,47 +253,37 @@ var commandListSubscriptions = &command{
 	},
 }
 
+var commandSetDefault = &command{
+	Execute: executeSetDefault,
+	AutocompleteData: &model.AutocompleteData{
+		Trigger:  "set-default",
+		HelpText: "Set the project used by the commands run in the current channel when they don't specify one. Run without arguments to see the default project.",
+		Arguments: []*model.AutocompleteArg{
+			projectVCSAutocompleteArg,
+			projectOrgAutocompleteArg,
+			projectRepoAutocompleteArg,
+		},
+		SubCommands: nil,
+	},
+}
+
+var commandUnsetDefault = &command{
+	Execute: executeUnsetDefault,
+	AutocompleteData: &model.AutocompleteData{
+		Trigger:  "unset-default",
+		HelpText: "Remove the default project of the current channel",
+	},
+}
+
 var commandBuild = &command{
 	Execute: executeBuild,
 	AutocompleteData: &model.AutocompleteData{
 		Trigger:  "build",
-		HelpText: "Trigger the specified build.",
+		HelpText: "Trigger the specified build. Run without arguments to pick the project in a dialog, or with only the project to get a field for each declared pipeline parameter.",
 		Arguments: []*model.AutocompleteArg{
-			{
-				HelpText: "VCS Alias",
-				Type:     model.AutocompleteArgTypeStaticList,
-				Required: true,
-				Data: &model.AutocompleteStaticListArg{
-					PossibleArguments: []model.AutocompleteListItem{
-						{
-							Item:     "github",
-							HelpText: "Use a Github repository",
-						},
-						{
-							Item:     "bitbucket",
-							HelpText: "Use a Bitbucket repository",
-						},
-					},
-				},
-			},
-			{
-				HelpText: "Org name on the VCS. For example org name for `github.com/foo/bar` would be `foo`.",
-				Type:     model.AutocompleteArgTypeText,
-				Required: true,
-				Data: &model.AutocompleteTextArg{
-					Hint:    "Org Name",
-					Pattern: "._+",
-				},
-			},
-			{
-				HelpText: "Repository name on the VCS. For example repository name for `github.com/foo/bar` would be `bar`.",
-				Type:     model.AutocompleteArgTypeText,
-				Required: true,
-				Data: &model.AutocompleteTextArg{
-					Hint:    "Repository Name",
-					Pattern: "._+",
-				},
-			},
+			projectVCSAutocompleteArg,
+			projectOrgAutocompleteArg,
+			projectRepoAutocompleteArg,
 			{
 				HelpText: "Head Type",
 				Type:     model.AutocompleteArgTypeStaticList,
@@ -228,6 +310,15 @@ var commandBuild = &command{
 					Pattern: "._+",
 				},
 			},
+			{
+				HelpText: "Pipeline parameters in the `name=value` format, separated by spaces. For example `deploy_env=staging run_e2e=true`.",
+				Type:     model.AutocompleteArgTypeText,
+				Required: false,
+				Data: &model.AutocompleteTextArg{
+					Hint:    "[name=value ...]",
+					Pattern: ".+",
+				},
+			},
 		},
 		SubCommands: nil,
 	},
@@ -237,50 +328,88 @@ var commandRecentBuilds = &command{
 	Execute: executeListRecentBuilds,
 	AutocompleteData: &model.AutocompleteData{
 		Trigger:  "recent-builds",
-		HelpText: "List recent builds of specified pipeline",
+		HelpText: "List the recent builds of the specified project",
 		Arguments: []*model.AutocompleteArg{
+			projectVCSAutocompleteArg,
+			projectOrgAutocompleteArg,
+			projectRepoAutocompleteArg,
+			{
+				HelpText: "Only list the builds of this workflow. Example - `build`, `release`.",
+				Type:     model.AutocompleteArgTypeText,
+				Required: false,
+				Data: &model.AutocompleteTextArg{
+					Hint:    "[Workflow name]",
+					Pattern: ".+",
+				},
+			},
 			{
-				HelpText: "VCS Alias",
+				Name:     "branch",
+				HelpText: "Only list the builds of this branch",
+				Type:     model.AutocompleteArgTypeText,
+				Required: false,
+				Data: &model.AutocompleteTextArg{
+					Hint:    "Branch name",
+					Pattern: ".+",
+				},
+			},
+			{
+				Name:     "status",
+				HelpText: "Only list the builds with the specified statuses. Can be comma-separated.",
 				Type:     model.AutocompleteArgTypeStaticList,
-				Required: true,
+				Required: false,
 				Data: &model.AutocompleteStaticListArg{
 					PossibleArguments: []model.AutocompleteListItem{
 						{
-							Item:     "github",
-							HelpText: "Use a Github repository",
+							Item:     serializer.StatusFailure,
+							HelpText: "Failed builds",
 						},
 						{
-							Item:     "bitbucket",
-							HelpText: "Use a Bitbucket repository",
+							Item:     serializer.StatusSuccess,
+							HelpText: "Successful builds",
+						},
+						{
+							Item:     serializer.StatusCanceled,
+							HelpText: "Canceled builds",
+						},
+						{
+							Item:     serializer.StatusOnHold,
+							HelpText: "Builds on hold which need an approval",
+						},
+						{
+							Item:     serializer.StatusRunning,
+							HelpText: "Running builds",
 						},
 					},
 				},
 			},
 			{
-				HelpText: "Org name on the VCS. For example org name for `github.com/foo/bar` would be `foo`.Org Name",
+				Name:     "since",
+				HelpText: "Only list the builds started after this time. Either a date such as `2020-09-25` or a duration such as `12h` or `7d`.",
 				Type:     model.AutocompleteArgTypeText,
-				Required: true,
+				Required: false,
 				Data: &model.AutocompleteTextArg{
-					Hint:    "Org name",
-					Pattern: "._+",
+					Hint:    "Date or duration",
+					Pattern: ".+",
 				},
 			},
 			{
-				HelpText: "Repository name on the VCS. For example repository name for `github.com/foo/bar` would be `bar`.",
+				Name:     "until",
+				HelpText: "Only list the builds started before this time. Either a date such as `2020-09-25` or a duration such as `12h` or `7d`.",
 				Type:     model.AutocompleteArgTypeText,
-				Required: true,
+				Required: false,
 				Data: &model.AutocompleteTextArg{
-					Hint:    "Repository Name",
-					Pattern: "._+",
+					Hint:    "Date or duration",
+					Pattern: ".+",
 				},
 			},
 			{
-				HelpText: "Workflow name to list recent builds of. Example - `build`, `release`.",
+				Name:     "limit",
+				HelpText: fmt.Sprintf("Number of builds to list. Defaults to %d, at most %d.", serializer.DefaultRecentBuildsLimit, serializer.MaxRecentBuildsLimit),
 				Type:     model.AutocompleteArgTypeText,
-				Required: true,
+				Required: false,
 				Data: &model.AutocompleteTextArg{
-					Hint:    "Workflow name",
-					Pattern: "._+",
+					Hint:    "Count",
+					Pattern: "[0-9]+",
 				},
 			},
 		},
@@ -288,102 +417,45 @@ var commandRecentBuilds = &command{
 	},
 }
 
-// These function would be useful in future when implementing on-premise VCS support.
-// var commandAddVCS = &command{
-// 	Execute: executeAddVCS,
-// 	AutocompleteData: &model.AutocompleteData{
-// 		Trigger:  "add vcs",
-// 		HelpText: "Add new VCS alias",
-// 		Arguments: []*model.AutocompleteArg{
-// 			{
-// 				HelpText: "Name to be used as VCS alias.",
-// 				Type:     model.AutocompleteArgTypeText,
-// 				Required: true,
-// 				Data: &model.AutocompleteTextArg{
-// 					Hint:    "VCS Alias",
-// 					Pattern: ".+",
-// 				},
-// 			},
-// 			{
-// 				HelpText: "Base URL of the VCS. This is the URL you see in CircleCI when viewing a build. For example - `github` is the base URL in `https://app.circleci.com/pipelines/github/foobar`",
-// 				Type:     model.AutocompleteArgTypeText,
-// 				Required: true,
-// 				Data: &model.AutocompleteTextArg{
-// 					Hint:    "VCS base URL",
-// 					Pattern: "._+",
-// 				},
-// 			},
-// 		},
-// 		SubCommands: nil,
-// 	},
-// }
-//
-// var commandDeleteVCS = &command{
-// 	Execute: executeDeleteVCS,
-// 	AutocompleteData: &model.AutocompleteData{
-// 		Trigger:  "delete vcs",
-// 		HelpText: "Delete an existing VCS alias",
-// 		Arguments: []*model.AutocompleteArg{
-// 			{
-// 				HelpText: "Name to be used as VCS alias",
-// 				Type:     model.AutocompleteArgTypeText,
-// 				Required: true,
-// 				Data: &model.AutocompleteTextArg{
-// 					Hint:    "VCS Alias",
-// 					Pattern: ".+",
-// 				},
-// 			},
-// 		},
-// 		SubCommands: nil,
-// 	},
-// }
-//
-// var commandListVCS = &command{
-// 	Execute: executeListVCS,
-// 	AutocompleteData: &model.AutocompleteData{
-// 		Trigger:  "list vcs",
-// 		HelpText: "List all available VCS.",
-// 	},
-// }
-
-var commandProjectSummary = &command{
-	Execute: executeProjectSummary,
+var commandAddVCS = &command{
+	Execute: executeAddVCS,
 	AutocompleteData: &model.AutocompleteData{
-		Trigger:  "project-insight",
-		HelpText: "Show project summary",
+		Trigger:  "vcs",
+		HelpText: "Add a GitHub Enterprise or self-hosted Bitbucket VCS. Only available to system admins.",
+		RoleID:   model.SYSTEM_ADMIN_ROLE_ID,
 		Arguments: []*model.AutocompleteArg{
 			{
-				HelpText: "VCS Alias",
+				HelpText: "VCS Type",
 				Type:     model.AutocompleteArgTypeStaticList,
 				Required: true,
 				Data: &model.AutocompleteStaticListArg{
 					PossibleArguments: []model.AutocompleteListItem{
 						{
-							Item:     "github",
-							HelpText: "Use a Github repository",
+							Item:     serializer.VCSTypeGithub,
+							HelpText: "GitHub Enterprise",
 						},
 						{
-							Item:     "bitbucket",
-							HelpText: "Use a Bitbucket repository",
+							Item:     serializer.VCSTypeBitbucket,
+							HelpText: "Self-hosted Bitbucket",
 						},
 					},
 				},
 			},
 			{
-				HelpText: "Org name on the VCS. For example org name for `github.com/foo/bar` would be `foo`.",
+				HelpText: "Name to be used as VCS alias.",
 				Type:     model.AutocompleteArgTypeText,
 				Required: true,
 				Data: &model.AutocompleteTextArg{
-					Hint:    "Org Name",
+					Hint:    "VCS Alias",
 					Pattern: "._+",
 				},
 			},
 			{
-				HelpText: "Repository name on the VCS. For example repository name for `github.com/foo/bar` would be `bar`.",
+				HelpText: "Base URL of the VCS. This is the URL of the repositories without the org and repo names. For example - `https://github.example.com`",
 				Type:     model.AutocompleteArgTypeText,
 				Required: true,
 				Data: &model.AutocompleteTextArg{
-					Hint:    "Repository Name",
+					Hint:    "VCS base URL",
 					Pattern: "._+",
 				},
 			},
@@ -392,47 +464,50 @@ var commandProjectSummary = &command{
 	},
 }
 
+var commandDeleteVCS = &command{
+	Execute: executeDeleteVCS,
+	AutocompleteData: &model.AutocompleteData{
+		Trigger:  "vcs",
+		HelpText: "Delete an existing VCS alias. Only available to system admins.",
+		RoleID:   model.SYSTEM_ADMIN_ROLE_ID,
+		Arguments: []*model.AutocompleteArg{
+			vcsAliasAutocompleteArg,
+		},
+		SubCommands: nil,
+	},
+}
+
+var commandListVCS = &command{
+	Execute: executeListVCS,
+	AutocompleteData: &model.AutocompleteData{
+		Trigger:  "vcs",
+		HelpText: "List all available VCS.",
+	},
+}
+
+var commandProjectSummary = &command{
+	Execute: executeProjectSummary,
+	AutocompleteData: &model.AutocompleteData{
+		Trigger:  "project-insight",
+		HelpText: "Show project summary",
+		Arguments: []*model.AutocompleteArg{
+			projectVCSAutocompleteArg,
+			projectOrgAutocompleteArg,
+			projectRepoAutocompleteArg,
+		},
+		SubCommands: nil,
+	},
+}
+
 var commandGetPipelineByNumber = &command{
 	Execute: executeGetPipelineByNumber,
 	AutocompleteData: &model.AutocompleteData{
 		Trigger:  "pipeline",
 		HelpText: "Get details of a pipeline.",
 		Arguments: []*model.AutocompleteArg{
-			{
-				HelpText: "VCS Alias",
-				Type:     model.AutocompleteArgTypeStaticList,
-				Required: true,
-				Data: &model.AutocompleteStaticListArg{
-					PossibleArguments: []model.AutocompleteListItem{
-						{
-							Item:     "github",
-							HelpText: "Use a Github repository",
-						},
-						{
-							Item:     "bitbucket",
-							HelpText: "Use a Bitbucket repository",
-						},
-					},
-				},
-			},
-			{
-				HelpText: "Org name on the VCS. For example org name for `github.com/foo/bar` would be `foo`.",
-				Type:     model.AutocompleteArgTypeText,
-				Required: true,
-				Data: &model.AutocompleteTextArg{
-					Hint:    "Org Name",
-					Pattern: "._+",
-				},
-			},
-			{
-				HelpText: "Repository name on the VCS. For example repository name for `github.com/foo/bar` would be `bar`.",
-				Type:     model.AutocompleteArgTypeText,
-				Required: true,
-				Data: &model.AutocompleteTextArg{
-					Hint:    "Repository Name",
-					Pattern: "._+",
-				},
-			},
+			projectVCSAutocompleteArg,
+			projectOrgAutocompleteArg,
+			projectRepoAutocompleteArg,
 			{
 				HelpText: "Pipeline Number",
 				Type:     model.AutocompleteArgTypeText,
@@ -453,38 +528,29 @@ var commandGetEnvironmentVariables = &command{
 		Trigger:  "environment",
 		HelpText: "Get masked environment variables for a project.",
 		Arguments: []*model.AutocompleteArg{
+			projectVCSAutocompleteArg,
+			projectOrgAutocompleteArg,
+			projectRepoAutocompleteArg,
+		},
+		SubCommands: nil,
+	},
+}
+
+var commandRecentWorkflowRuns = &command{
+	Execute: executeRecentWorkflowRuns,
+	AutocompleteData: &model.AutocompleteData{
+		Trigger:  "workflow-insights",
+		HelpText: "Get insight for a workflow's recent runs.",
+		Arguments: []*model.AutocompleteArg{
+			projectVCSAutocompleteArg,
+			projectOrgAutocompleteArg,
+			projectRepoAutocompleteArg,
 			{
-				HelpText: "VCS Alias",
-				Type:     model.AutocompleteArgTypeStaticList,
-				Required: true,
-				Data: &model.AutocompleteStaticListArg{
-					PossibleArguments: []model.AutocompleteListItem{
-						{
-							Item:     "github",
-							HelpText: "Use a Github repository",
-						},
-						{
-							Item:     "bitbucket",
-							HelpText: "Use a Bitbucket repository",
-						},
-					},
-				},
-			},
-			{
-				HelpText: "Org name on the VCS. For example org name for `github.com/foo/bar` would be `foo`.",
-				Type:     model.AutocompleteArgTypeText,
-				Required: true,
-				Data: &model.AutocompleteTextArg{
-					Hint:    "Org Name",
-					Pattern: "._+",
-				},
-			},
-			{
-				HelpText: "Repository name on the VCS. For example repository name for `github.com/foo/bar` would be `bar`.",
+				HelpText: "Workflow Name",
 				Type:     model.AutocompleteArgTypeText,
 				Required: true,
 				Data: &model.AutocompleteTextArg{
-					Hint:    "Repository Name",
+					Hint:    "Workflow's name",
 					Pattern: "._+",
 				},
 			},
@@ -493,56 +559,29 @@ var commandGetEnvironmentVariables = &command{
 	},
 }
 
-var commandRecentWorkflowRuns = &command{
-	Execute: executeRecentWorkflowRuns,
+var commandWebhookSecret = &command{
+	Execute: executeWebhookSecret,
 	AutocompleteData: &model.AutocompleteData{
-		Trigger:  "workflow-insights",
-		HelpText: "Get insight for a workflow's recent runs.",
+		Trigger:  "webhook-secret",
+		HelpText: "Get the secret and URL to use for CircleCI native webhooks of a project. Only available to system admins.",
+		RoleID:   model.SYSTEM_ADMIN_ROLE_ID,
 		Arguments: []*model.AutocompleteArg{
+			projectVCSAutocompleteArg,
+			projectOrgAutocompleteArg,
+			projectRepoAutocompleteArg,
 			{
-				HelpText: "VCS Alias",
+				Name:     "regenerate",
+				HelpText: "Generate a new secret. Webhooks signed with the old secret will be rejected.",
 				Type:     model.AutocompleteArgTypeStaticList,
-				Required: true,
+				Required: false,
 				Data: &model.AutocompleteStaticListArg{
 					PossibleArguments: []model.AutocompleteListItem{
 						{
-							Item:     "github",
-							HelpText: "Use a Github repository",
-						},
-						{
-							Item:     "bitbucket",
-							HelpText: "Use a Bitbucket repository",
+							Item: "true",
 						},
 					},
 				},
 			},
-			{
-				HelpText: "Org name on the VCS. For example org name for `github.com/foo/bar` would be `foo`.",
-				Type:     model.AutocompleteArgTypeText,
-				Required: true,
-				Data: &model.AutocompleteTextArg{
-					Hint:    "Org Name",
-					Pattern: "._+",
-				},
-			},
-			{
-				HelpText: "Repository name on the VCS. For example repository name for `github.com/foo/bar` would be `bar`.",
-				Type:     model.AutocompleteArgTypeText,
-				Required: true,
-				Data: &model.AutocompleteTextArg{
-					Hint:    "Repository Name",
-					Pattern: "._+",
-				},
-			},
-			{
-				HelpText: "Workflow Name",
-				Type:     model.AutocompleteArgTypeText,
-				Required: true,
-				Data: &model.AutocompleteTextArg{
-					Hint:    "Workflow's name",
-					Pattern: "._+",
-				},
-			},
 		},
 		SubCommands: nil,
 	},
@@ -562,38 +601,67 @@ var CircleCICommandHandler = Handler{
 			SubCommands: []*model.AutocompleteData{
 				commandConnect.AutocompleteData,
 				commandDisconnect.AutocompleteData,
+				commandMe.AutocompleteData,
+				commandNotifyMe.AutocompleteData,
 				commandSubscribe.AutocompleteData,
 				commandUnsubscribe.AutocompleteData,
 				commandListSubscriptions.AutocompleteData,
+				commandSetDefault.AutocompleteData,
+				commandUnsetDefault.AutocompleteData,
 				commandBuild.AutocompleteData,
 				commandRecentBuilds.AutocompleteData,
-				// These can be used later when adding Github and Bitbucket on-premise support
-				//commandAddVCS.AutocompleteData,
-				//commandDeleteVCS.AutocompleteData,
-				//commandListVCS.AutocompleteData,
+				{
+					Trigger:     "add",
+					HelpText:    "Add a custom VCS",
+					RoleID:      model.SYSTEM_ADMIN_ROLE_ID,
+					SubCommands: []*model.AutocompleteData{commandAddVCS.AutocompleteData},
+				},
+				{
+					Trigger:     "delete",
+					HelpText:    "Delete a custom VCS",
+					RoleID:      model.SYSTEM_ADMIN_ROLE_ID,
+					SubCommands: []*model.AutocompleteData{commandDeleteVCS.AutocompleteData},
+				},
+				{
+					Trigger:     "list",
+					HelpText:    "List the available VCS",
+					SubCommands: []*model.AutocompleteData{commandListVCS.AutocompleteData},
+				},
 				commandProjectSummary.AutocompleteData,
 				commandGetPipelineByNumber.AutocompleteData,
 				commandGetEnvironmentVariables.AutocompleteData,
 				commandRecentWorkflowRuns.AutocompleteData,
+				commandWebhookSecret.AutocompleteData,
+				commandAdmin,
 			},
 		},
 	},
 	handlers: map[string]HandlerFunc{
 		"connect":            commandConnect.Execute,
 		"disconnect":         commandDisconnect.Execute,
+		"me":                 commandMe.Execute,
+		"notify-me":          commandNotifyMe.Execute,
 		"subscribe":          commandSubscribe.Execute,
 		"unsubscribe":        commandUnsubscribe.Execute,
 		"list-subscriptions": commandListSubscriptions.Execute,
+		"set-default":        commandSetDefault.Execute,
+		"unset-default":      commandUnsetDefault.Execute,
 		"build":              commandBuild.Execute,
 		"recent-builds":      commandRecentBuilds.Execute,
-		// These can be used later when adding Github and Bitbucket on-premise support
-		//"add/vcs":            commandAddVCS.Execute,
-		//"delete/vcs":         commandDeleteVCS.Execute,
-		//"list/vcs":           commandListVCS.Execute,
-		"project-insight":   commandProjectSummary.Execute,
-		"pipeline":          commandGetPipelineByNumber.Execute,
-		"environment":       commandGetEnvironmentVariables.Execute,
-		"workflow-insights": commandRecentWorkflowRuns.Execute,
+		"add/vcs":            commandAddVCS.Execute,
+		"delete/vcs":         commandDeleteVCS.Execute,
+		"list/vcs":           commandListVCS.Execute,
+		"project-insight":    commandProjectSummary.Execute,
+		"pipeline":           commandGetPipelineByNumber.Execute,
+		"environment":        commandGetEnvironmentVariables.Execute,
+		"workflow-insights":  commandRecentWorkflowRuns.Execute,
+		"webhook-secret":     commandWebhookSecret.Execute,
+
+		"admin/map-user":           commandAdminMapUser.Execute,
+		"admin/unmap-user":         commandAdminUnmapUser.Execute,
+		"admin/list-user-mappings": commandAdminListUserMappings.Execute,
+		"admin/reencrypt-tokens":   commandAdminReencryptTokens.Execute,
+		"admin/subscriptions":      commandAdminSubscriptions.Execute,
 	},
 	defaultHandler: func(context *model.CommandArgs, args ...string) (*model.CommandResponse, *model.AppError) {
 		return util.SendEphemeralCommandResponse(invalidCommand)
@@ -601,21 +669,31 @@ var CircleCICommandHandler = Handler{
 }
 
 func executeSubscribe(context *model.CommandArgs, args ...string) (*model.CommandResponse, *model.AppError) {
-	if len(args) != 3 {
-		return util.SendEphemeralCommandResponse("Invalid number of arguments. syntax: `/circleci subscribe [vcs-alias] [org-name] [repo-name]`")
+	args, namedArgs, err := util.ParseNamedArgs(args)
+	if err != nil {
+		return util.SendEphemeralCommandResponse(err.Error())
 	}
 
-	vcs, err := service.GetVCS(args[0])
+	project, args, err := getCommandProject(context, args)
 	if err != nil {
 		return util.SendEphemeralCommandResponse(err.Error())
 	}
 
+	if len(args) != 0 {
+		return util.SendEphemeralCommandResponse("Invalid number of arguments. syntax: `/circleci subscribe [vcs-alias] [org-name] [repo-name] [--branch pattern] [--job pattern] [--status status] [--tags-only true] [--group-by-workflow true]`")
+	}
+
 	newSubscription := serializer.Subscription{
-		VCSType:   vcs.Alias,
-		BaseURL:   vcs.BaseURL,
-		OrgName:   args[1],
-		RepoName:  args[2],
+		VCSType:   project.VCS.Alias,
+		BaseURL:   project.VCS.BaseURL,
+		OrgName:   project.Org,
+		RepoName:  project.Repo,
 		ChannelID: context.ChannelId,
+		CreatorID: context.UserId,
+	}
+
+	if err := applySubscriptionArgs(&newSubscription, namedArgs); err != nil {
+		return util.SendEphemeralCommandResponse(err.Error())
 	}
 
 	if err := newSubscription.Validate(); err != nil {
@@ -630,20 +708,20 @@ func executeSubscribe(context *model.CommandArgs, args ...string) (*model.Comman
 }
 
 func executeUnsubscribe(context *model.CommandArgs, args ...string) (*model.CommandResponse, *model.AppError) {
-	if len(args) != 3 {
-		return util.SendEphemeralCommandResponse("Invalid number of arguments. syntax: `/circleci unsubscribe [vcs-alias] [org-name] [repo-name]`")
-	}
-
-	vcs, err := service.GetVCS(args[0])
+	project, args, err := getCommandProject(context, args)
 	if err != nil {
 		return util.SendEphemeralCommandResponse(err.Error())
 	}
 
+	if len(args) != 0 {
+		return util.SendEphemeralCommandResponse("Invalid number of arguments. syntax: `/circleci unsubscribe [vcs-alias] [org-name] [repo-name]`")
+	}
+
 	subscription := serializer.Subscription{
-		VCSType:   vcs.Alias,
-		BaseURL:   vcs.BaseURL,
-		OrgName:   args[1],
-		RepoName:  args[2],
+		VCSType:   project.VCS.Alias,
+		BaseURL:   project.VCS.BaseURL,
+		OrgName:   project.Org,
+		RepoName:  project.Repo,
 		ChannelID: context.ChannelId,
 	}
 
@@ -664,463 +742,590 @@ func executeListSubscriptions(context *model.CommandArgs, args ...string) (*mode
 		return util.SendEphemeralCommandResponse("You have no notifications subscribed to this channel.\nUse `/circleci subscribe` to create a subscription.")
 	}
 
-	message := "| VcsType | BaseURL | Organization | Repository |\n| :-- | --: | :-- | :-- |\n"
+	message := "| VcsType | BaseURL | Organization | Repository | Filters |\n| :-- | --: | :-- | :-- | :-- |\n"
 	for _, s := range subscriptions {
-		message += fmt.Sprintf("| %s | %s | %s | %s |\n", s.VCSType, s.BaseURL, s.OrgName, s.RepoName)
+		filters := s.Filters.String()
+		if s.GroupByWorkflow {
+			filters += " (grouped by workflow)"
+		}
+		message += fmt.Sprintf("| %s | %s | %s | %s | %s |\n", s.VCSType, s.BaseURL, s.OrgName, s.RepoName, filters)
 	}
 
 	return util.SendEphemeralCommandResponse(message)
 }
 
+// applySubscriptionArgs sets the filters and options specified as named arguments on the subscription
+func applySubscriptionArgs(subscription *serializer.Subscription, namedArgs map[string][]string) error {
+	subscription.Filters = serializer.SubscriptionFilters{
+		Branches: namedArgs["branch"],
+		Jobs:     namedArgs["job"],
+		Statuses: namedArgs["status"],
+	}
+
+	for name, values := range namedArgs {
+		switch name {
+		case "branch", "job", "status":
+		case "tags-only", "group-by-workflow":
+			value, err := strconv.ParseBool(values[0])
+			if err != nil {
+				return fmt.Errorf("invalid value `%s` for argument `--%s`", values[0], name)
+			}
+
+			if name == "tags-only" {
+				subscription.Filters.TagsOnly = value
+			} else {
+				subscription.GroupByWorkflow = value
+			}
+		default:
+			return fmt.Errorf("unknown argument `--%s`", name)
+		}
+	}
+
+	return nil
+}
+
+func executeSetDefault(context *model.CommandArgs, args ...string) (*model.CommandResponse, *model.AppError) {
+	if len(args) == 0 {
+		defaultProject, err := store.GetDefaultProject(context.ChannelId)
+		if err != nil {
+			return util.SendEphemeralCommandResponse("Failed to get the default project of the channel. Please try again later. If the problem persists, contact your system administrator.")
+		}
+		if defaultProject == nil {
+			return util.SendEphemeralCommandResponse("This channel has no default project. Use `/circleci set-default <vcs alias> <org> <repo>` to set it.")
+		}
+
+		return util.SendEphemeralCommandResponse(fmt.Sprintf("The default project of this channel is `%s`.", defaultProject.String()))
+	}
+
+	project, args, err := parseCommandProject(args)
+	if err != nil {
+		return util.SendEphemeralCommandResponse(err.Error())
+	}
+
+	if project == nil || len(args) != 0 {
+		return util.SendEphemeralCommandResponse("Invalid number of arguments. syntax: `/circleci set-default <vcs alias> <org> <repo>` or `/circleci set-default <project slug or URL>`")
+	}
+
+	defaultProject := &serializer.DefaultProject{
+		VCSAlias: project.VCS.Alias,
+		OrgName:  project.Org,
+		RepoName: project.Repo,
+	}
+
+	if err := store.SaveDefaultProject(context.ChannelId, defaultProject); err != nil {
+		return util.SendEphemeralCommandResponse("Failed to set the default project. Please try again later. If the problem persists, contact your system administrator.")
+	}
+
+	return util.SendEphemeralCommandResponse(fmt.Sprintf("The default project of this channel is set to `%s`. The project arguments can now be omitted from the commands run in this channel.", defaultProject.String()))
+}
+
+func executeUnsetDefault(context *model.CommandArgs, args ...string) (*model.CommandResponse, *model.AppError) {
+	if err := store.DeleteDefaultProject(context.ChannelId); err != nil {
+		return util.SendEphemeralCommandResponse("Failed to remove the default project. Please try again later. If the problem persists, contact your system administrator.")
+	}
+
+	return util.SendEphemeralCommandResponse("The default project of this channel has been removed.")
+}
+
 func executeConnect(ctx *model.CommandArgs, args ...string) (*model.CommandResponse, *model.AppError) {
-	// we need the auth token
-	if len(args) < 1 {
-		return util.SendEphemeralCommandResponse("Please specify the auth token.")
+	// The token is entered in a dialog so that it doesn't end up in the command history
+	if len(args) > 0 {
+		return util.SendEphemeralCommandResponse("The auth token can no longer be passed to the command. Please run `/circleci connect` without arguments and enter the token in the dialog. As the token was typed in the command, you may want to revoke it and create a new one.")
 	}
 
-	authToken := args[0]
-	if err := store.SaveAuthToken(ctx.UserId, authToken); err != nil {
-		return util.SendEphemeralCommandResponse("Failed to save the auth token. Please try again later. If the problem persists, contact your system administrator.")
+	if err := service.OpenConnectDialog(ctx.TriggerId); err != nil {
+		return util.SendEphemeralCommandResponse("Failed to open the connect dialog. Please try again later. If the problem persists, contact your system administrator.")
 	}
 
-	return util.SendEphemeralCommandResponse("Successfully saved auth token.")
+	return &model.CommandResponse{}, nil
 }
 
 func executeDisconnect(ctx *model.CommandArgs, args ...string) (*model.CommandResponse, *model.AppError) {
 	authToken, err := store.GetCircleCIToken(ctx.UserId)
-	if err != nil {
-		return util.SendEphemeralCommandResponse("Failed to get the auth token. Please try again later. If the problem persists, contact your system administrator.")
+	if err != nil && err != store.ErrUndecryptableToken {
+		return util.SendEphemeralCommandResponse(service.AuthTokenErrorMessage(err))
 	}
-	if authToken == "" {
+	if authToken == "" && err == nil {
 		return util.SendEphemeralCommandResponse("Your CircleCI account is not connected to Mattermost. Please use `/circleci connect` to connect your CircleCI and Mattermost accounts.")
 	}
 
+	// A token which can't be decrypted anymore can still be removed
+	if authToken != "" {
+		if err := service.UnlinkCircleCIUser(ctx.UserId, authToken); err != nil {
+			config.Mattermost.LogWarn("Failed to unlink the CircleCI user.", "UserID", ctx.UserId, "Error", err.Error())
+		}
+	}
+
 	if appErr := config.Mattermost.KVDelete(store.CircleCIAuthTokenKey(ctx.UserId)); appErr != nil {
 		config.Mattermost.LogError("Unable to disconnect from CircleCI.", "Error", appErr.Error())
 		return nil, appErr
 	}
 
-	return util.SendEphemeralCommandResponse("Successfully disconnected.")
+	return util.SendEphemeralCommandResponse("Successfully disconnected.")
+}
+
+func executeMe(ctx *model.CommandArgs, args ...string) (*model.CommandResponse, *model.AppError) {
+	authToken, err := store.GetCircleCIToken(ctx.UserId)
+	if err != nil {
+		return util.SendEphemeralCommandResponse(service.AuthTokenErrorMessage(err))
+	}
+	if authToken == "" {
+		return util.SendEphemeralCommandResponse("Your CircleCI account is not connected to Mattermost. Please use `/circleci connect` to connect your CircleCI and Mattermost accounts.")
+	}
+
+	client := service.NewCircleCIClient(authToken)
+	requestCtx, cancel := context.WithTimeout(context.Background(), service.RequestDeadline)
+	defer cancel()
+
+	user, err := client.GetCurrentUser(requestCtx)
+	if err != nil {
+		if err == service.ErrInvalidAuthToken {
+			return util.SendEphemeralCommandResponse("Your CircleCI auth token has been revoked or has expired. Please use `/circleci connect` to connect your account again.")
+		}
+
+		config.Mattermost.LogError("Failed to fetch the CircleCI user.", "UserID", ctx.UserId, "Error", err.Error())
+		return util.SendEphemeralCommandResponse("Unable to connect to CircleCI. Please try again later. Error: " + err.Error())
+	}
+
+	collaborations, err := client.ListCollaborations(requestCtx)
+	if err != nil {
+		config.Mattermost.LogWarn("Failed to fetch the CircleCI collaborations.", "UserID", ctx.UserId, "Error", err.Error())
+	}
+
+	message := fmt.Sprintf("You are connected to CircleCI as **%s** (`%s`). Your auth token is valid.\n", user.Name, user.Login)
+	switch {
+	case err != nil:
+		message += "\nUnable to fetch the organizations your auth token can access."
+	case len(collaborations) == 0:
+		message += "\nYour auth token can't access any organization."
+	default:
+		message += "\n| Organization | VCS |\n| :-- | :-- |\n"
+		for _, collaboration := range collaborations {
+			message += fmt.Sprintf("| %s | %s |\n", collaboration.Name, collaboration.VcsType)
+		}
+	}
+
+	return util.SendEphemeralCommandResponse(message)
+}
+
+func executeNotifyMe(ctx *model.CommandArgs, args ...string) (*model.CommandResponse, *model.AppError) {
+	if len(args) != 1 {
+		setting, err := store.GetNotifyMeSetting(ctx.UserId)
+		if err != nil {
+			return util.SendEphemeralCommandResponse("Failed to fetch your personal notification setting. Please try again later. If the problem persists, contact your system administrator.")
+		}
+
+		return util.SendEphemeralCommandResponse(fmt.Sprintf("Your personal notifications are set to `%s`. Use this command as `/circleci notify-me [on | failures | off]` to change them.", setting))
+	}
+
+	setting := serializer.NormalizeNotifyMeSetting(strings.ToLower(args[0]))
+	if setting == "" {
+		return util.SendEphemeralCommandResponse(fmt.Sprintf("Invalid setting `%s`. Please specify one of `on`, `%s` or `%s`.", args[0], serializer.NotifyMeFailures, serializer.NotifyMeOff))
+	}
+
+	if setting != serializer.NotifyMeOff {
+		authToken, err := store.GetCircleCIToken(ctx.UserId)
+		if err != nil {
+			return util.SendEphemeralCommandResponse(service.AuthTokenErrorMessage(err))
+		}
+		if authToken == "" {
+			return util.SendEphemeralCommandResponse("Your CircleCI account is not connected to Mattermost. Please use `/circleci connect` to connect your CircleCI and Mattermost accounts.")
+		}
+
+		// Refresh the mapping used for matching the webhooks to the user
+		if _, err := service.LinkCircleCIUser(ctx.UserId, authToken); err != nil {
+			if errors.Is(err, service.ErrInvalidAuthToken) {
+				return util.SendEphemeralCommandResponse("Your CircleCI auth token has been revoked or has expired. Please use `/circleci connect` to connect your account again.")
+			}
+
+			config.Mattermost.LogWarn("Failed to link the CircleCI user.", "UserID", ctx.UserId, "Error", err.Error())
+			return util.SendEphemeralCommandResponse("Unable to fetch your CircleCI account. Please try again later.")
+		}
+	}
+
+	if err := store.SaveNotifyMeSetting(ctx.UserId, setting); err != nil {
+		return util.SendEphemeralCommandResponse("Failed to save the setting. Please try again later. If the problem persists, contact your system administrator.")
+	}
+
+	switch setting {
+	case serializer.NotifyMeAll:
+		return util.SendEphemeralCommandResponse("You will get a direct message when a workflow you triggered finishes.")
+	case serializer.NotifyMeFailures:
+		return util.SendEphemeralCommandResponse("You will get a direct message when a workflow you triggered fails.")
+	default:
+		return util.SendEphemeralCommandResponse("Personal notifications are turned off.")
+	}
+}
+
+func executeListRecentBuilds(ctx *model.CommandArgs, args ...string) (*model.CommandResponse, *model.AppError) {
+	args, namedArgs, err := util.ParseNamedArgs(args)
+	if err != nil {
+		return util.SendEphemeralCommandResponse(err.Error())
+	}
+
+	project, args, err := getCommandProject(ctx, args)
+	if err != nil {
+		return util.SendEphemeralCommandResponse(err.Error())
+	}
+
+	if len(args) > 1 {
+		return util.SendEphemeralCommandResponse("Invalid syntax. Use this command as `/circleci recent-builds [vcs alias] [org name] [repo name] [workflow name] [--branch name] [--status status] [--since time] [--until time] [--limit count]`")
+	}
+
+	authToken, err := store.GetCircleCIToken(ctx.UserId)
+	if err != nil {
+		return util.SendEphemeralCommandResponse(service.AuthTokenErrorMessage(err))
+	}
+	if authToken == "" {
+		return util.SendEphemeralCommandResponse("Your CircleCI account is not connected to Mattermost. Please use `/circleci connect` to connect your CircleCI and Mattermost accounts.")
+	}
+	client := service.NewCircleCIClient(authToken)
+
+	query := serializer.RecentBuildsQuery{
+		ProjectSlug: project.GetSlug(),
+		Limit:       serializer.DefaultRecentBuildsLimit,
+	}
+	if len(args) == 1 {
+		query.Workflow = args[0]
+	}
+
+	if err := applyRecentBuildsArgs(&query, namedArgs, time.Now()); err != nil {
+		return util.SendEphemeralCommandResponse(err.Error())
+	}
+
+	return executeWithDeadline(ctx, func(c context.Context) (*model.CommandResponse, *model.AppError) {
+		return postRecentBuilds(c, ctx, client, query, project.Org, project.Repo)
+	})
+}
+
+func postRecentBuilds(c context.Context, ctx *model.CommandArgs, client *service.CircleCIClient, query serializer.RecentBuildsQuery, org, repo string) (*model.CommandResponse, *model.AppError) {
+	builds, err := service.ListRecentBuilds(c, client, query)
+	if err != nil {
+		config.Mattermost.LogError("Failed to list recent builds.", "ProjectSlug", query.ProjectSlug, "Error", err.Error())
+		return util.SendEphemeralCommandResponse("Unable to fetch data from CircleCI. Make sure the auth token is still valid and try again.")
+	}
+
+	if len(builds) == 0 {
+		return util.SendEphemeralCommandResponse("No builds found matching the specified filters.")
+	}
+
+	attachment := util.BaseSlackAttachment()
+	attachment.Title = fmt.Sprintf("Recent builds of %s/%s", org, repo)
+	if query.Workflow != "" {
+		attachment.Title += fmt.Sprintf(" for the `%s` workflow", query.Workflow)
+	}
+	attachment.Text = getRecentBuildsTable(query.ProjectSlug, builds)
+
+	post := &model.Post{
+		UserId:    config.BotUserID,
+		ChannelId: ctx.ChannelId,
+	}
+
+	model.ParseSlackAttachment(post, []*model.SlackAttachment{attachment})
+	if _, err := config.Mattermost.CreatePost(post); err != nil {
+		config.Mattermost.LogError(fmt.Sprintf("Failed to create post for recent builds. ChannelID: %s, error: %s", ctx.ChannelId, err.Error()))
+		return util.SendEphemeralCommandResponse("Failed to create post for recent builds. Please try again later. If the problem persists, contact your system administrator.")
+	}
+
+	return &model.CommandResponse{}, nil
+}
+
+// applyRecentBuildsArgs sets the filters specified as named arguments on the recent-builds query
+func applyRecentBuildsArgs(query *serializer.RecentBuildsQuery, namedArgs map[string][]string, now time.Time) error {
+	for name, values := range namedArgs {
+		switch name {
+		case "branch":
+			query.Branch = values[0]
+		case "status":
+			query.Statuses = nil
+			for _, value := range values {
+				status := serializer.NormalizeStatus(value)
+				if !funk.ContainsString(serializer.ValidStatuses, status) {
+					return fmt.Errorf("invalid status `%s`. Valid statuses are: %s", value, strings.Join(serializer.ValidStatuses, ", "))
+				}
+				query.Statuses = append(query.Statuses, status)
+			}
+		case "since", "until":
+			t, err := parseTimeArg(values[0], now)
+			if err != nil {
+				return fmt.Errorf("invalid value `%s` for argument `--%s`. Use a date such as `2020-09-25`, a time such as `2020-09-25T15:04:05Z` or a duration before now such as `12h` or `7d`", values[0], name)
+			}
+
+			if name == "since" {
+				query.Since = t
+			} else {
+				query.Until = t
+			}
+		case "limit":
+			limit, err := strconv.Atoi(values[0])
+			if err != nil || limit < 1 || limit > serializer.MaxRecentBuildsLimit {
+				return fmt.Errorf("invalid value `%s` for argument `--limit`. It must be a number between 1 and %d", values[0], serializer.MaxRecentBuildsLimit)
+			}
+			query.Limit = limit
+		default:
+			return fmt.Errorf("unknown argument `--%s`", name)
+		}
+	}
+
+	if !query.Since.IsZero() && !query.Until.IsZero() && query.Until.Before(query.Since) {
+		return fmt.Errorf("`--until` must be after `--since`")
+	}
+
+	return nil
+}
+
+// parseTimeArg parses a date, a RFC 3339 time, or a duration such as `12h` or `7d` counted back from now
+func parseTimeArg(value string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+
+	if strings.HasSuffix(value, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(value, "d"))
+		if err != nil || days < 0 {
+			return time.Time{}, fmt.Errorf("invalid number of days `%s`", value)
+		}
+		return now.AddDate(0, 0, -days), nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return time.Time{}, fmt.Errorf("invalid duration `%s`", value)
+	}
+
+	return now.Add(-d), nil
 }
 
-func executeListRecentBuilds(ctx *model.CommandArgs, args ...string) (*model.CommandResponse, *model.AppError) {
-	if len(args) < 4 {
-		return util.SendEphemeralCommandResponse("Invalid  syntax. Use this command as `/circleci recent-builds <vcs alias> <org name> <repo name> <workflow name>`")
+func getRecentBuildsTable(projectSlug string, builds []*serializer.RecentBuild) string {
+	table := "| Pipeline | Workflow | Branch | Status | Triggered By | Started | Duration |\n| :-- | :-- | :-- | :-- | :-- | :-- | :-- |\n"
+	for _, build := range builds {
+		head := build.Branch
+		if build.Tag != "" {
+			head = "tag " + build.Tag
+		}
+
+		duration := "-"
+		if d := build.GetDuration(); d > 0 {
+			duration = d.String()
+		}
+
+		table += fmt.Sprintf(
+			"| [#%d](%s) | %s | %s | %s %s | %s | %s | %s |\n",
+			build.PipelineNumber,
+			serializer.GetPipelineWorkflowURL(projectSlug, build.PipelineNumber, build.WorkflowID),
+			build.WorkflowName,
+			head,
+			serializer.GetStatusIcon(serializer.NormalizeStatus(build.Status)),
+			build.Status,
+			build.TriggeredBy,
+			build.CreatedAt.UTC().Format("2006-01-02 15:04 MST"),
+			duration,
+		)
 	}
 
+	return table
+}
+
+func executeBuild(ctx *model.CommandArgs, args ...string) (*model.CommandResponse, *model.AppError) {
 	authToken, err := store.GetCircleCIToken(ctx.UserId)
 	if err != nil {
-		return util.SendEphemeralCommandResponse("Failed to get the auth token. Please try again later. If the problem persists, contact your system administrator.")
+		return util.SendEphemeralCommandResponse(service.AuthTokenErrorMessage(err))
 	}
 	if authToken == "" {
 		return util.SendEphemeralCommandResponse("Your CircleCI account is not connected to Mattermost. Please use `/circleci connect` to connect your CircleCI and Mattermost accounts.")
 	}
-	client := util.GetCircleciClient(authToken)
 
-	vcsAlias, org, repo, workflow := args[0], args[1], args[2], args[3]
-
-	vcs, err := service.GetVCS(vcsAlias)
-	if err != nil {
-		return util.SendEphemeralCommandResponse("Failed to get VCS details. Please try again later. If the problem persists, contact your system administrator.")
+	// The dialog is opened for picking the project, or for filling the parameters of the project
+	project, args, err := getCommandProject(ctx, args)
+	if err == errMissingProject && len(args) == 0 {
+		return openBuildDialog(ctx, authToken, "")
 	}
-
-	builds, resp, err := client.InsightsApi.GetProjectWorkflowRuns(
-		context.TODO(),
-		fmt.Sprintf("%s/%s/%s", vcs.Type, org, repo),
-		workflow,
-		time.Now().Add(-24*time.Hour),
-		time.Now(),
-		nil,
-	)
-
 	if err != nil {
-		return util.SendEphemeralCommandResponse("Unable to connect to circleci. Make sure the auth token is still valid. Error: " + err.Error())
-	}
-	if resp != nil {
-		defer resp.Body.Close()
+		return util.SendEphemeralCommandResponse(err.Error())
 	}
 
-	workflowCache := map[string]circleci2.Workflow{}
-	pipelineCache := map[string]circleci2.Pipeline{}
-	userCache := map[string]circleci2.User{}
-
-	attachment := util.BaseSlackAttachment()
+	if len(args) == 0 {
+		return openBuildDialog(ctx, authToken, project.GetSlug())
+	}
 
-	for _, build := range builds.Items[:10] {
-		var workflow circleci2.Workflow
+	if len(args) < 2 {
+		return util.SendEphemeralCommandResponse(fmt.Sprintf("Please specify what to build against as `%s <name>` or `%s <name>`, or run the command without them to open the build dialog.", service.HeadTypeBranch, service.HeadTypeTag))
+	}
 
-		if _, exists := workflowCache[build.Id]; exists {
-			workflow = workflowCache[build.Id]
-		} else {
-			workflow, resp, err = client.WorkflowApi.GetWorkflowById(context.TODO(), build.Id)
-			if err != nil {
-				config.Mattermost.LogError("Unable to fetch workflow for ID: " + build.Id + ". Error: " + err.Error())
-				return util.SendEphemeralCommandResponse("Unable to fetch data from CircleCI. Please try again.")
-			}
-			if resp != nil {
-				defer resp.Body.Close()
-			}
-		}
+	headType, head := args[0], args[1]
 
-		var pipeline circleci2.Pipeline
+	if headType != service.HeadTypeBranch && headType != service.HeadTypeTag {
+		return util.SendEphemeralCommandResponse(fmt.Sprintf("Invalid head type. Please specify one of `%s` or `%s`", service.HeadTypeBranch, service.HeadTypeTag))
+	}
 
-		if _, exists := pipelineCache[workflow.PipelineId]; exists {
-			pipeline = pipelineCache[workflow.PipelineId]
-		} else {
-			pipeline, resp, err = client.PipelineApi.GetPipelineById(context.TODO(), workflow.PipelineId)
-			if err != nil {
-				config.Mattermost.LogError("Unable to fetch pipeline for ID: " + workflow.PipelineId + ". Error: " + err.Error())
-				return util.SendEphemeralCommandResponse("Unable to fetch data from CircleCI. Please try again.")
-			}
-			if resp != nil {
-				defer resp.Body.Close()
-			}
-		}
+	params, err := serializer.ParsePipelineParameters(args[2:])
+	if err != nil {
+		return util.SendEphemeralCommandResponse(err.Error())
+	}
 
-		var triggeredBy circleci2.User
+	client := service.NewCircleCIClient(authToken)
+	projectSlug := project.GetSlug()
 
-		if _, exists := userCache[workflow.StartedBy]; exists {
-			triggeredBy = userCache[workflow.StartedBy]
-		} else {
-			triggeredBy, resp, err = client.UserApi.GetUser(context.TODO(), workflow.StartedBy)
-			if err != nil {
-				config.Mattermost.LogError("Unable to fetch user for ID: " + workflow.StartedBy + ". Error: " + err.Error())
-				return util.SendEphemeralCommandResponse("Unable to fetch data from CircleCI. Please try again.")
-			}
-			if resp != nil {
-				defer resp.Body.Close()
-			}
-		}
+	return executeWithDeadline(ctx, func(c context.Context) (*model.CommandResponse, *model.AppError) {
+		return triggerBuild(c, ctx, client, projectSlug, headType, head, params)
+	})
+}
 
-		attachment.Fields = append(attachment.Fields, []*model.SlackAttachmentField{
-			{
-				Short: false,
-				Title: "",
-				Value: "***",
-			},
-			{
-				Short: false,
-				Title: fmt.Sprintf("Build #%d", workflow.PipelineNumber),
-				Value: fmt.Sprintf("https://app.circleci.com/pipelines/%s/%s/%d/workflows/%s/", vcs, repo, workflow.PipelineNumber, workflow.Id),
-			},
-			{
-				Short: true,
-				Title: "Triggered By",
-				Value: triggeredBy.Login,
-			},
-			{
-				Short: true,
-				Title: "Status",
-				Value: build.Status,
-			},
-			{
-				Short: true,
-				Title: "VCS",
-				Value: pipeline.Vcs.OriginRepositoryUrl,
-			},
-			{
-				Short: true,
-				Title: "Branch",
-				Value: pipeline.Vcs.Branch,
-			},
-			{
-				Short: true,
-				Title: "Pipeline Number",
-				Value: fmt.Sprintf("%d", workflow.PipelineNumber),
-			},
-		}...)
+func openBuildDialog(ctx *model.CommandArgs, authToken, projectSlug string) (*model.CommandResponse, *model.AppError) {
+	if err := service.OpenBuildDialog(ctx.TriggerId, authToken, projectSlug); err != nil {
+		return util.SendEphemeralCommandResponse("Failed to open the build dialog. Please try again later. If the problem persists, contact your system administrator.")
 	}
 
-	post := &model.Post{
-		UserId:    config.BotUserID,
-		ChannelId: ctx.ChannelId,
-	}
+	return &model.CommandResponse{}, nil
+}
 
-	model.ParseSlackAttachment(post, []*model.SlackAttachment{attachment})
-	if _, err := config.Mattermost.CreatePost(post); err != nil {
-		config.Mattermost.LogError(fmt.Sprintf("Failed to create post for triggered workflow. ChannelID: %s, error: %s", ctx.ChannelId, err.Error()))
-		return util.SendEphemeralCommandResponse("Failed to create post for triggered workflow. The build has been triggered though. You can view it in CircleCI.")
+func triggerBuild(c context.Context, ctx *model.CommandArgs, client *service.CircleCIClient, projectSlug, headType, head string, params serializer.PipelineParameters) (*model.CommandResponse, *model.AppError) {
+	if err := service.TriggerBuild(c, client, ctx.UserId, ctx.ChannelId, projectSlug, headType, head, params); err != nil {
+		return util.SendEphemeralCommandResponse(err.Error())
 	}
 
 	return &model.CommandResponse{}, nil
 }
 
-func executeBuild(ctx *model.CommandArgs, args ...string) (*model.CommandResponse, *model.AppError) {
-	authToken, err := store.GetCircleCIToken(ctx.UserId)
-	if err != nil {
-		return util.SendEphemeralCommandResponse("Failed to get the auth token. Please try again later. If the problem persists, contact your system administrator.")
-	}
-	if authToken == "" {
-		return util.SendEphemeralCommandResponse("Your CircleCI account is not connected to Mattermost. Please use `/circleci connect` to connect your CircleCI and Mattermost accounts.")
+func executeAddVCS(context *model.CommandArgs, args ...string) (*model.CommandResponse, *model.AppError) {
+	if !isSystemAdmin(context.UserId) {
+		return util.SendEphemeralCommandResponse("Only system admins can add a VCS.")
 	}
 
-	// we need the auth token
-	if len(args) < 5 {
-		return util.SendEphemeralCommandResponse("Please specify the account, repo and branch names.")
+	if len(args) < 3 {
+		return util.SendEphemeralCommandResponse("Invalid number of arguments. Use this command as `/circleci add vcs [github | bitbucket] [alias] [base URL]`")
 	}
 
-	vcsAlias, org, repo, headType, head := args[0], args[1], args[2], args[3], args[4]
+	vcsType, alias, baseURL := args[0], args[1], strings.TrimSuffix(args[2], "/")
 
-	vcs, err := service.GetVCS(vcsAlias)
-	if err != nil {
-		return util.SendEphemeralCommandResponse("Failed to get VCS details. Please try again later. If the problem persists, contact your system administrator.")
+	existingVCS, err := service.GetVCS(alias)
+	if err != nil && err != service.ErrVCSNotFound {
+		return util.SendEphemeralCommandResponse(
+			"Failed to check for existing VCS with same alias. Please try again later. If the problem persists, contact your system administrator.",
+		)
 	}
 
-	client := util.GetCircleciClient(authToken)
+	if existingVCS != nil {
+		return util.SendEphemeralCommandResponse(fmt.Sprintf("Another VCS exists with the same alias. Please delete existing VCS first if you want to update it. Alias: `%s`, base URL: `%s`", existingVCS.Alias, existingVCS.BaseURL))
+	}
 
-	body := map[string]string{}
+	vcs := &serializer.VCS{
+		Alias:   alias,
+		BaseURL: baseURL,
+		Type:    vcsType,
+	}
 
-	switch headType {
-	case HeadTypeBranch:
-		body[HeadTypeBranch] = head
-	case HeadTypeTag:
-		body[HeadTypeTag] = head
-	default:
-		return util.SendEphemeralCommandResponse(fmt.Sprintf("Invalid head type. Please specify one of `%s` or `%s`", HeadTypeBranch, HeadTypeTag))
+	if err := vcs.Validate(); err != nil {
+		return util.SendEphemeralCommandResponse(err.Error())
 	}
 
-	build, response, err := client.PipelineApi.TriggerPipeline(context.TODO(), vcs.Type+"/"+org+"/"+repo, &circleci2.PipelineApiTriggerPipelineOpts{
-		Body: optional.NewInterface(body),
-	})
+	if err := service.AddVCS(vcs); err != nil {
+		return util.SendEphemeralCommandResponse("Failed to save VCS. Please try again later. If the problem persists, contact your system administrator.")
+	}
 
-	if err != nil {
-		config.Mattermost.LogError(fmt.Sprintf(
-			"Failed to trigger build. VCS slug: %s, repo: %s, head type: %s, head: %s, error: %s, response: %v",
-			vcsAlias,
-			repo,
-			headType,
-			head,
-			err.Error(),
-			response,
-		))
+	message := fmt.Sprintf("Successfully added VCS with alias `%s` and base URL `%s`", vcs.Alias, vcs.BaseURL)
 
-		var message string
+	_, _ = config.Mattermost.CreatePost(&model.Post{
+		UserId:    config.BotUserID,
+		ChannelId: context.ChannelId,
+		Message:   message,
+	})
 
-		if response != nil && response.StatusCode == http.StatusNotFound {
-			message = "Unable to trigger build. Either the specified workflow doesn't exist or the auth token is not valid."
-		} else {
-			message = "Unable to trigger build."
-		}
+	return &model.CommandResponse{}, nil
+}
 
-		return util.SendEphemeralCommandResponse(message)
+func executeDeleteVCS(context *model.CommandArgs, args ...string) (*model.CommandResponse, *model.AppError) {
+	if !isSystemAdmin(context.UserId) {
+		return util.SendEphemeralCommandResponse("Only system admins can delete a VCS.")
 	}
-	if response != nil {
-		defer response.Body.Close()
-	}
-
-	workflows, response, err := client.PipelineApi.ListWorkflowsByPipelineId(context.TODO(), build.Id, nil)
-	if err != nil {
-		config.Mattermost.LogError(fmt.Sprintf(
-			"Failed to fetch pipeline details. Pipeline ID: %s, VCS slug: %s, repo: %s, head type: %s, head: %s, error: %s, response: %v",
-			build.Id,
-			vcsAlias,
-			repo,
-			headType,
-			head,
-			err.Error(),
-			response,
-		))
 
-		return util.SendEphemeralCommandResponse("Successfully trigger build but failed to fetch triggered build's details. You  can still view the triggered build in CircleCI.")
-	}
-	if response != nil {
-		defer response.Body.Close()
+	if len(args) < 1 {
+		return util.SendEphemeralCommandResponse("Invalid number of arguments. Use this command as `/circleci delete vcs [alias]`")
 	}
 
-	attachmentFields := make([]*model.SlackAttachmentField, len(workflows.Items))
+	alias := args[0]
 
-	for i, workflow := range workflows.Items {
-		attachmentFields[i] = &model.SlackAttachmentField{
-			Short: false,
-			Title: "Workflow: " + strings.Title(workflow.Name),
-			Value: fmt.Sprintf("https://app.circleci.com/pipelines/%s/%d/workflows/%s", vcsAlias+"/"+repo, build.Number, workflow.Id),
+	if _, err := service.GetVCS(alias); err != nil {
+		if err == service.ErrVCSNotFound {
+			return util.SendEphemeralCommandResponse("No VCS exists with provided alias.")
 		}
+		return util.SendEphemeralCommandResponse("Failed to check VCS. Please try again later. If the problem persists, contact your system administrator.")
 	}
 
-	attachmentFields = append(
-		attachmentFields,
-		&model.SlackAttachmentField{
-			Title: "Build Number",
-			Value: fmt.Sprintf("%d", build.Number),
-			Short: true,
-		},
-		&model.SlackAttachmentField{
-			Title: "Build ID",
-			Value: build.Id,
-			Short: true,
-		},
-		&model.SlackAttachmentField{
-			Title: "State",
-			Value: build.State,
-			Short: true,
-		},
-	)
+	if err := service.DeleteVCS(alias); err != nil {
+		return util.SendEphemeralCommandResponse(fmt.Sprintf("Failed to delete VCS. Error: %s", err.Error()))
+	}
 
-	attachment := util.BaseSlackAttachment()
-	attachment.Pretext = fmt.Sprintf("CircleCI build %d initiated successfully.", build.Number)
-	attachment.Text = fmt.Sprintf("CircleCI build %d initiated successfully.", build.Number)
-	attachment.Fields = attachmentFields
+	message := fmt.Sprintf("Successfully deleted VCS with alias `%s`", alias)
 
-	post := &model.Post{
+	_, _ = config.Mattermost.CreatePost(&model.Post{
 		UserId:    config.BotUserID,
-		ChannelId: ctx.ChannelId,
-	}
+		ChannelId: context.ChannelId,
+		Message:   message,
+	})
 
-	model.ParseSlackAttachment(post, []*model.SlackAttachment{attachment})
+	return &model.CommandResponse{}, nil
+}
 
-	if _, err := config.Mattermost.CreatePost(post); err != nil {
-		config.Mattermost.LogError(fmt.Sprintf(
-			"Failed to create post for triggered build. Projet: %s, err: %s",
-			vcs.Type+"/"+org+"/"+repo,
-			err.Error()),
-		)
+func executeListVCS(context *model.CommandArgs, args ...string) (*model.CommandResponse, *model.AppError) {
+	vcsList, err := service.GetVCSList()
+	if err != nil {
+		return util.SendEphemeralCommandResponse("Failed to fetch list of VCS. Please try again later. If the problem persists, contact your system administrator.")
 	}
 
-	return &model.CommandResponse{}, nil
-}
+	message := "Available VCS -\n\n| No.  | Type | Alias | Base URL |\n|:------------|:------------|:------------|:------------|\n"
+	for i, vcs := range vcsList {
+		message += fmt.Sprintf("|%d|%s|%s|%s|\n", i+1, vcs.Type, vcs.Alias, vcs.BaseURL)
+	}
 
-// These function would be useful in future when implementing on-premise VCS support.
-// func executeAddVCS(context *model.CommandArgs, args ...string) (*model.CommandResponse, *model.AppError) {
-// 	config.Mattermost.LogInfo(fmt.Sprintf("%v", args))
-// 	if len(args) < 3 {
-// 		return util.SendEphemeralCommandResponse("Invalid number of arguments. Use this command as `/circleci add vcs [github | bitbucket] [alias] [base URL]`")
-// 	}
-//
-// 	vcsType, alias, baseURL := args[0], args[1], args[2]
-//
-// 	existingVCS, err := service.GetVCS(alias)
-// 	if err != nil {
-// 		return util.SendEphemeralCommandResponse(
-// 			"Failed to check for existing VCS with same alias. Please try again later. If the problem persists, contact your system administrator.",
-// 		)
-// 	}
-//
-// 	if existingVCS != nil {
-// 		return util.SendEphemeralCommandResponse(fmt.Sprintf("Another VCS exists with the same alias. Please delete existing VCS first if you want to update it. Alias: `%s`, base URL: `%s`", existingVCS.Alias, existingVCS.BaseURL))
-// 	}
-//
-// 	vcs := &serializer.VCS{
-// 		Alias:   alias,
-// 		BaseURL: baseURL,
-// 		Type:    vcsType,
-// 	}
-//
-// 	if err := service.AddVCS(vcs); err != nil {
-// 		return util.SendEphemeralCommandResponse("Failed to save VCS. Please try again later. If the problem persists, contact your system administrator.")
-// 	}
-//
-// 	message := fmt.Sprintf("Successfully added VCS with alias `%s` and base URL `%s`", vcs.Alias, vcs.BaseURL)
-//
-// 	_, _ = config.Mattermost.CreatePost(&model.Post{
-// 		UserId:    config.BotUserID,
-// 		ChannelId: context.ChannelId,
-// 		Message:   message,
-// 	})
-//
-// 	return &model.CommandResponse{}, nil
-// }
-//
-// func executeDeleteVCS(context *model.CommandArgs, args ...string) (*model.CommandResponse, *model.AppError) {
-// 	if len(args) < 1 {
-// 		return util.SendEphemeralCommandResponse("Invalid number of arguments. Use this command as `/circleci delete vcs [alias]`")
-// 	}
-//
-// 	alias := args[0]
-//
-// 	existingVCS, err := service.GetVCS(alias)
-// 	if err != nil {
-// 		return util.SendEphemeralCommandResponse("Failed to check VCS. Please try again later. If the problem persists, contact your system administrator.")
-// 	}
-//
-// 	if existingVCS == nil {
-// 		return util.SendEphemeralCommandResponse("No VCS exists with provided alias.")
-// 	}
-//
-// 	if err := service.DeleteVCS(alias); err != nil {
-// 		return util.SendEphemeralCommandResponse("Failed to delete VCS. Please try again later. If the problem persists, contact your system administrator.")
-// 	}
-//
-// 	message := fmt.Sprintf("Successfully deleted VCS with alias `%s`", alias)
-//
-// 	_, _ = config.Mattermost.CreatePost(&model.Post{
-// 		UserId:    config.BotUserID,
-// 		ChannelId: context.ChannelId,
-// 		Message:   message,
-// 	})
-//
-// 	return &model.CommandResponse{}, nil
-// }
-//
-// func executeListVCS(context *model.CommandArgs, args ...string) (*model.CommandResponse, *model.AppError) {
-// 	vcsList, err := service.GetVCSList()
-// 	if err != nil {
-// 		return util.SendEphemeralCommandResponse("Failed to fetch list of VCS. Please try again later. If the problem persists, contact your system administrator.")
-// 	}
-//
-// 	message := "Available VCS -\n\n| No.  | Type | Alias | Base URL |\n|:------------|:------------|:------------|:------------|\n"
-// 	for i, vcs := range vcsList {
-// 		message += fmt.Sprintf("|%d|%s|%s|%s|\n", i+1, vcs.Type, vcs.Alias, vcs.BaseURL)
-// 	}
-//
-// 	_, _ = config.Mattermost.CreatePost(&model.Post{
-// 		UserId:    config.BotUserID,
-// 		ChannelId: context.ChannelId,
-// 		Message:   message,
-// 	})
-//
-// 	return &model.CommandResponse{}, nil
-// }
+	return util.SendEphemeralCommandResponse(message)
+}
 
 // executeProjectSummary - uses insight API
 func executeProjectSummary(ctx *model.CommandArgs, args ...string) (*model.CommandResponse, *model.AppError) {
-	if len(args) < 3 {
-		return util.SendEphemeralCommandResponse("Incorrect syntax. Use this command as `/circleci project-insight <VCS alias> <org> <repo>`")
-	}
-
-	vcsAlias, org, repo := args[0], args[1], args[2]
-	vcs, err := service.GetVCS(vcsAlias)
+	project, args, err := getCommandProject(ctx, args)
 	if err != nil {
-		return util.SendEphemeralCommandResponse("Failed to get VCS details. Please try again later. If the problem persists, contact your system administrator.")
+		return util.SendEphemeralCommandResponse(err.Error())
+	}
+	if len(args) > 0 {
+		return util.SendEphemeralCommandResponse("Incorrect syntax. Use this command as `/circleci project-insight [vcs alias] [org] [repo]` or `/circleci project-insight <project URL>`")
 	}
 
 	authToken, err := store.GetCircleCIToken(ctx.UserId)
 	if err != nil {
-		return util.SendEphemeralCommandResponse("Failed to get the auth token. Please try again later. If the problem persists, contact your system administrator.")
+		return util.SendEphemeralCommandResponse(service.AuthTokenErrorMessage(err))
 	}
 	if authToken == "" {
 		return util.SendEphemeralCommandResponse("Your CircleCI account is not connected to Mattermost. Please use `/circleci connect` to connect your CircleCI and Mattermost accounts.")
 	}
-	client := util.GetCircleciClient(authToken)
+	requestCtx, cancel := context.WithTimeout(context.Background(), service.RequestDeadline)
+	defer cancel()
 
-	insights, response, err := client.InsightsApi.GetProjectWorkflowMetrics(context.TODO(), fmt.Sprintf("%s/%s/%s", vcs.Type, org, repo), nil)
+	insights, err := service.NewCircleCIClient(authToken).GetProjectWorkflowMetrics(requestCtx, project.GetSlug())
 	if err != nil {
-		config.Mattermost.LogError(fmt.Sprintf(
-			"Failed to fetch project summary. Project slug: %s, error: %s",
-			args[1],
-			err.Error(),
-		))
+		config.Mattermost.LogError("Failed to fetch project summary.", "Project", project.GetSlug(), "Error", err.Error())
 
 		return util.SendEphemeralCommandResponse(
 			"Failed to fetch project summary from CircleCI. Please try again later. If the problem persists, contact your system administrator.",
 		)
 	}
 
-	if response != nil {
-		defer response.Body.Close()
-	}
-
 	post := &model.Post{
 		UserId:    config.BotUserID,
 		ChannelId: ctx.ChannelId,
 	}
 
-	attachments := make([]*model.SlackAttachment, len(insights.Items))
+	attachments := make([]*model.SlackAttachment, len(insights))
 
-	for i, insight := range insights.Items {
+	for i, insight := range insights {
 		attachment := util.BaseSlackAttachment()
 		attachment.Title = fmt.Sprintf(
 			"Project Summary: %s | %s | %s to %s",
-			fmt.Sprintf("%s/%s/%s", vcs.Type, org, repo),
+			project.GetSlug(),
 			strings.Title(insight.Name),
 			insight.WindowStart.Format(time.UnixDate),
 			insight.WindowEnd.Format(time.UnixDate),
@@ -1200,7 +1405,7 @@ func executeProjectSummary(ctx *model.CommandArgs, args ...string) (*model.Comma
 
 	_, appErr := config.Mattermost.CreatePost(post)
 	if appErr != nil {
-		config.Mattermost.LogError(fmt.Sprintf("Failed to create post for project summary. Project: %s, error: %s", args[1], appErr.Error()))
+		config.Mattermost.LogError("Failed to create post for project summary.", "Project", project.GetSlug(), "Error", appErr.Error())
 		return util.SendEphemeralCommandResponse(
 			"Failed to create post for project summary. Please try again later. If the problem persists, contact your system administrator.",
 		)
@@ -1211,39 +1416,43 @@ func executeProjectSummary(ctx *model.CommandArgs, args ...string) (*model.Comma
 
 // executeGetPipelineByNumber - uses pipeline API
 func executeGetPipelineByNumber(ctx *model.CommandArgs, args ...string) (*model.CommandResponse, *model.AppError) {
-	if len(args) < 4 {
-		return util.SendEphemeralCommandResponse("Incorrect syntax. Use this command as `/circleci pipeline <vcs alias> <org> <repo> <pipeline number>`")
+	project, args, err := getCommandProject(ctx, args)
+	if err != nil {
+		return util.SendEphemeralCommandResponse(err.Error())
 	}
 
-	vcsAlias, org, repo, pipelineNumber := args[0], args[1], args[2], args[3]
+	pipelineNumber := project.PipelineNumber
+	if len(args) > 0 {
+		pipelineNumber = args[0]
+	}
 
-	vcs, err := service.GetVCS(vcsAlias)
-	if err != nil {
-		return util.SendEphemeralCommandResponse("Failed to get VCS details. Please try again later. If the problem persists, contact your system administrator.")
+	if pipelineNumber == "" {
+		return util.SendEphemeralCommandResponse("Incorrect syntax. Use this command as `/circleci pipeline [vcs alias] [org] [repo] <pipeline number>` or `/circleci pipeline <pipeline URL>`")
 	}
 
 	authToken, err := store.GetCircleCIToken(ctx.UserId)
 	if err != nil {
-		return util.SendEphemeralCommandResponse("Failed to get the auth token. Please try again later. If the problem persists, contact your system administrator.")
+		return util.SendEphemeralCommandResponse(service.AuthTokenErrorMessage(err))
 	}
 
 	if authToken == "" {
 		return util.SendEphemeralCommandResponse("Your CircleCI account is not connected to Mattermost. Please use `/circleci connect` to connect your CircleCI and Mattermost accounts.")
 	}
 
-	client := util.GetCircleciClient(authToken)
-	pipeline, pipelineResponse, err := client.PipelineApi.GetPipelineByNumber(context.TODO(), fmt.Sprintf("%s/%s/%s", vcs.Type, org, repo), pipelineNumber)
+	client := service.NewCircleCIClient(authToken)
+	projectSlug := project.GetSlug()
 
-	if pipelineResponse != nil {
-		defer pipelineResponse.Body.Close()
-	}
+	return executeWithDeadline(ctx, func(c context.Context) (*model.CommandResponse, *model.AppError) {
+		return postPipelineByNumber(c, ctx, client, projectSlug, pipelineNumber)
+	})
+}
 
+func postPipelineByNumber(c context.Context, ctx *model.CommandArgs, client *service.CircleCIClient, projectSlug, pipelineNumber string) (*model.CommandResponse, *model.AppError) {
+	pipeline, err := client.GetPipelineByNumber(c, projectSlug, pipelineNumber)
 	if err != nil {
 		config.Mattermost.LogError(fmt.Sprintf(
-			"Failed to get pipeline data by number. VCS: %s, org: %s, repo: %s, pipeline number: %s, error: %s",
-			vcsAlias,
-			org,
-			repo,
+			"Failed to get pipeline data by number. Project: %s, pipeline number: %s, error: %s",
+			projectSlug,
 			pipelineNumber,
 			err.Error(),
 		))
@@ -1251,18 +1460,11 @@ func executeGetPipelineByNumber(ctx *model.CommandArgs, args ...string) (*model.
 		return util.SendEphemeralCommandResponse("Failed to get pipeline details. Please try again later. If the problem persists, contact your system administrator.")
 	}
 
-	workflows, workflowResponse, err := client.PipelineApi.ListWorkflowsByPipelineId(context.TODO(), pipeline.Id, nil)
-
-	if workflowResponse != nil {
-		defer workflowResponse.Body.Close()
-	}
-
+	workflows, err := client.ListPipelineWorkflows(c, pipeline.Id)
 	if err != nil {
 		config.Mattermost.LogError(fmt.Sprintf(
-			"Failed to get pipeline's workflows . VCS: %s, org: %s, repo: %s, pipeline ID: %s, error: %s",
-			vcsAlias,
-			org,
-			repo,
+			"Failed to get pipeline's workflows. Project: %s, pipeline ID: %s, error: %s",
+			projectSlug,
 			pipeline.Id,
 			err.Error(),
 		))
@@ -1270,28 +1472,30 @@ func executeGetPipelineByNumber(ctx *model.CommandArgs, args ...string) (*model.
 		return util.SendEphemeralCommandResponse("Failed to get pipeline's workflows. Please try again later. If the problem persists, contact your system administrator.")
 	}
 
-	jobsByWorkflow := map[circleci2.Workflow1]circleci2.WorkflowJobListResponse{}
-
-	for i, workflow := range workflows.Items {
-		jobs, resp, err := client.WorkflowApi.ListWorkflowJobs(context.TODO(), workflow.Id)
+	jobsByWorkflow := make([][]circleci2.Job, len(workflows))
+	err = util.RunConcurrently(c, len(workflows), service.MaxConcurrentRequests, func(c context.Context, i int) error {
+		jobs, err := client.ListWorkflowJobs(c, workflows[i].Id)
 		if err != nil {
-			config.Mattermost.LogError(fmt.Sprintf(
-				"Failed to get workflow's job. VCS: %s, org: %s, repo: %s, pipeline ID: %s, workflow ID: %s, error: %s",
-				vcsAlias,
-				org,
-				repo,
-				pipeline.Id,
-				workflow.Id,
-				err.Error(),
-			))
-
-			return util.SendEphemeralCommandResponse("Failed to get workflow's jobs. Please try again later. If the problem persists, contact your system administrator.")
-		}
-		if resp != nil {
-			defer resp.Body.Close()
+			return fmt.Errorf("failed to get the jobs of workflow %s: %w", workflows[i].Id, err)
 		}
 
-		jobsByWorkflow[workflows.Items[i]] = jobs
+		jobsByWorkflow[i] = jobs
+		return nil
+	})
+	if err != nil {
+		config.Mattermost.LogError(fmt.Sprintf(
+			"Failed to get workflow's job. Project: %s, pipeline ID: %s, error: %s",
+			projectSlug,
+			pipeline.Id,
+			err.Error(),
+		))
+
+		return util.SendEphemeralCommandResponse("Failed to get workflow's jobs. Please try again later. If the problem persists, contact your system administrator.")
+	}
+
+	triggeredBy := ""
+	if pipeline.Trigger != nil && pipeline.Trigger.Actor != nil {
+		triggeredBy = pipeline.Trigger.Actor.Login
 	}
 
 	attachment := util.BaseSlackAttachment()
@@ -1305,7 +1509,7 @@ func executeGetPipelineByNumber(ctx *model.CommandArgs, args ...string) (*model.
 		{
 			Short: true,
 			Title: "triggered By",
-			Value: pipeline.Trigger.Actor.Login,
+			Value: triggeredBy,
 		},
 		{
 			Short: false,
@@ -1314,7 +1518,7 @@ func executeGetPipelineByNumber(ctx *model.CommandArgs, args ...string) (*model.
 		},
 	}
 
-	for workflow, jobs := range jobsByWorkflow {
+	for i, workflow := range workflows {
 		fields := []*model.SlackAttachmentField{
 			{
 				Short: true,
@@ -1328,7 +1532,7 @@ func executeGetPipelineByNumber(ctx *model.CommandArgs, args ...string) (*model.
 			},
 		}
 
-		for _, job := range jobs.Items {
+		for _, job := range jobsByWorkflow[i] {
 			jobFields := []*model.SlackAttachmentField{
 				{
 					Short: false,
@@ -1382,10 +1586,8 @@ func executeGetPipelineByNumber(ctx *model.CommandArgs, args ...string) (*model.
 
 	if _, appErr := config.Mattermost.CreatePost(post); appErr != nil {
 		config.Mattermost.LogError(fmt.Sprintf(
-			"Failed to create post for workflow by number. VCS: %s, org: %s, repo: %s, pipeline ID: %s, channelID: %s, error: %s",
-			vcsAlias,
-			org,
-			repo,
+			"Failed to create post for workflow by number. Project: %s, pipeline ID: %s, channelID: %s, error: %s",
+			projectSlug,
 			pipeline.Id,
 			ctx.ChannelId,
 			appErr.Error(),
@@ -1399,44 +1601,38 @@ func executeGetPipelineByNumber(ctx *model.CommandArgs, args ...string) (*model.
 
 // executeGetAllEnvironmentVariables - uses project API
 func executeGetAllEnvironmentVariables(ctx *model.CommandArgs, args ...string) (*model.CommandResponse, *model.AppError) {
-	if len(args) < 3 {
-		return util.SendEphemeralCommandResponse("Incorrect syntax. Use this command as `/circleci env vars <vcs alias> <org> <repo>`")
-	}
-
-	vcsAlias, org, repo := args[0], args[1], args[2]
-
-	vcs, err := service.GetVCS(vcsAlias)
+	project, args, err := getCommandProject(ctx, args)
 	if err != nil {
-		return util.SendEphemeralCommandResponse("Failed to get VCS details. Please try again later. If the problem persists, contact your system administrator.")
+		return util.SendEphemeralCommandResponse(err.Error())
+	}
+	if len(args) > 0 {
+		return util.SendEphemeralCommandResponse("Incorrect syntax. Use this command as `/circleci environment [vcs alias] [org] [repo]` or `/circleci environment <project URL>`")
 	}
 
 	authToken, err := store.GetCircleCIToken(ctx.UserId)
 	if err != nil {
-		return util.SendEphemeralCommandResponse("Failed to get the auth token. Please try again later. If the problem persists, contact your system administrator.")
+		return util.SendEphemeralCommandResponse(service.AuthTokenErrorMessage(err))
 	}
 
 	if authToken == "" {
 		return util.SendEphemeralCommandResponse("Your CircleCI account is not connected to Mattermost. Please use `/circleci connect` to connect your CircleCI and Mattermost accounts.")
 	}
 
-	client := util.GetCircleciClient(authToken)
-	projectSlug := fmt.Sprintf("%s/%s/%s", vcs.Type, org, repo)
+	projectSlug := project.GetSlug()
+	requestCtx, cancel := context.WithTimeout(context.Background(), service.RequestDeadline)
+	defer cancel()
 
-	envVars, response, err := client.ProjectApi.ListEnvVars(context.TODO(), projectSlug)
+	envVars, err := service.NewCircleCIClient(authToken).ListEnvVars(requestCtx, projectSlug)
 	if err != nil {
 		config.Mattermost.LogError(fmt.Sprintf("Could not fetch the list of Env Vars. Channel ID: %s, error: %s", ctx.ChannelId, err.Error()))
 		return util.SendEphemeralCommandResponse("Could not fetch the list of Env Vars. Please make sure your CircleCI Auth Token is still valid and try again.")
 	}
 
-	if response != nil {
-		defer response.Body.Close()
-	}
-
 	attachment := util.BaseSlackAttachment()
 	attachment.Title = "Masked Environment Variables for : " + projectSlug
-	attachment.Fields = make([]*model.SlackAttachmentField, len(envVars.Items))
+	attachment.Fields = make([]*model.SlackAttachmentField, len(envVars))
 
-	for i, envVar := range envVars.Items {
+	for i, envVar := range envVars {
 		attachment.Fields[i] = &model.SlackAttachmentField{
 			Short: true,
 			Title: envVar.Name,
@@ -1458,41 +1654,42 @@ func executeGetAllEnvironmentVariables(ctx *model.CommandArgs, args ...string) (
 }
 
 func executeRecentWorkflowRuns(ctx *model.CommandArgs, args ...string) (*model.CommandResponse, *model.AppError) {
-	if len(args) < 4 {
-		return util.SendEphemeralCommandResponse("Incorrect syntax. Use this command as `/circleci workflow-insights <vcs alias> <org> <repo> <workflow name>`")
+	project, args, err := getCommandProject(ctx, args)
+	if err != nil {
+		return util.SendEphemeralCommandResponse(err.Error())
 	}
 
-	vcsAlias, org, repo, workflowName := args[0], args[1], args[2], args[3]
-
-	vcs, err := service.GetVCS(vcsAlias)
-	if err != nil {
-		return util.SendEphemeralCommandResponse("Failed to get VCS details. Please try again later. If the problem persists, contact your system administrator.")
+	if len(args) < 1 && project.WorkflowID == "" {
+		return util.SendEphemeralCommandResponse("Incorrect syntax. Use this command as `/circleci workflow-insights [vcs alias] [org] [repo] <workflow name>` or `/circleci workflow-insights <workflow URL>`")
 	}
 
 	authToken, err := store.GetCircleCIToken(ctx.UserId)
 	if err != nil {
-		return util.SendEphemeralCommandResponse("Failed to get the auth token. Please try again later. If the problem persists, contact your system administrator.")
+		return util.SendEphemeralCommandResponse(service.AuthTokenErrorMessage(err))
 	}
 
 	if authToken == "" {
 		return util.SendEphemeralCommandResponse("Your CircleCI account is not connected to Mattermost. Please use `/circleci connect` to connect your CircleCI and Mattermost accounts.")
 	}
 
-	client := util.GetCircleciClient(authToken)
-	projectSlug := fmt.Sprintf("%s/%s/%s", vcs.Type, org, repo)
+	client := service.NewCircleCIClient(authToken)
+	projectSlug := project.GetSlug()
+	requestCtx, cancel := context.WithTimeout(context.Background(), service.RequestDeadline)
+	defer cancel()
 
-	workflowRuns, response, err := client.InsightsApi.GetProjectWorkflowRuns(
-		context.TODO(),
-		projectSlug,
-		workflowName,
-		time.Now().Add(-24*time.Hour),
-		time.Now(),
-		nil,
-	)
-	if response != nil {
-		defer response.Body.Close()
+	workflowName := ""
+	if len(args) > 0 {
+		workflowName = args[0]
+	} else {
+		workflow, err := client.GetWorkflow(requestCtx, project.WorkflowID)
+		if err != nil {
+			config.Mattermost.LogError("Failed to fetch the workflow from CircleCI.", "WorkflowID", project.WorkflowID, "Error", err.Error())
+			return util.SendEphemeralCommandResponse("Failed to fetch the workflow. Please make sure your CircleCI Auth Token is still valid and try again.")
+		}
+		workflowName = workflow.Name
 	}
 
+	workflowRuns, err := client.ListProjectWorkflowRuns(requestCtx, projectSlug, workflowName, time.Now().Add(-24*time.Hour), time.Now())
 	if err != nil {
 		config.Mattermost.LogError(fmt.Sprintf(
 			"Failed to fetch workflow insight from CircleCI. Project slug: %s, workflow name: %s, err: %s",
@@ -1510,8 +1707,8 @@ func executeRecentWorkflowRuns(ctx *model.CommandArgs, args ...string) (*model.C
 
 	// Having too many attachments in a post can exceed the safe limit.
 	// That's why here we're splitting it into multiple posts.
-	for i := 0; i < len(workflowRuns.Items); i += runsPerPost {
-		items := workflowRuns.Items[i:funk.MinInt([]int{i + runsPerPost, len(workflowRuns.Items)}).(int)]
+	for i := 0; i < len(workflowRuns); i += runsPerPost {
+		items := workflowRuns[i:funk.MinInt([]int{i + runsPerPost, len(workflowRuns)}).(int)]
 
 		attachments := make([]*model.SlackAttachment, len(items))
 
@@ -1569,3 +1766,171 @@ func executeRecentWorkflowRuns(ctx *model.CommandArgs, args ...string) (*model.C
 
 	return &model.CommandResponse{}, nil
 }
+
+func executeWebhookSecret(ctx *model.CommandArgs, args ...string) (*model.CommandResponse, *model.AppError) {
+	if !isSystemAdmin(ctx.UserId) {
+		return util.SendEphemeralCommandResponse("Only system admins can manage webhook secrets.")
+	}
+
+	args, namedArgs, err := util.ParseNamedArgs(args)
+	if err != nil {
+		return util.SendEphemeralCommandResponse(err.Error())
+	}
+
+	project, args, err := getCommandProject(ctx, args)
+	if err != nil {
+		return util.SendEphemeralCommandResponse(err.Error())
+	}
+	if len(args) > 0 {
+		return util.SendEphemeralCommandResponse("Incorrect syntax. Use this command as `/circleci webhook-secret [vcs alias] [org] [repo] [--regenerate true]`")
+	}
+
+	regenerate := false
+	if values := namedArgs["regenerate"]; len(values) > 0 {
+		regenerate, _ = strconv.ParseBool(values[0])
+	}
+
+	projectSlug := project.GetSlug()
+	secret, err := service.GetOrCreateWebhookSecret(projectSlug, regenerate)
+	if err != nil {
+		return util.SendEphemeralCommandResponse("Failed to get the webhook secret. Please try again later. If the problem persists, contact your system administrator.")
+	}
+
+	siteURL := ""
+	if mmConfig := config.Mattermost.GetConfig(); mmConfig.ServiceSettings.SiteURL != nil {
+		siteURL = strings.TrimSuffix(*mmConfig.ServiceSettings.SiteURL, "/")
+	}
+
+	message := fmt.Sprintf(
+		"Add a webhook in the CircleCI project settings of `%s` with the following details and select the `Workflow Completed` and `Job Completed` events.\n"+
+			"- **Receiver URL:** `%s/api/v1/circleci-webhook?project=%s`\n"+
+			"- **Secret token:** `%s`",
+		projectSlug,
+		siteURL+config.URLPluginBase,
+		url.QueryEscape(projectSlug),
+		secret,
+	)
+
+	return util.SendEphemeralCommandResponse(message)
+}
+
+func isSystemAdmin(userID string) bool {
+	return config.Mattermost.HasPermissionTo(userID, model.PERMISSION_MANAGE_SYSTEM)
+}
+
+// getVCSErrorMessage returns the message shown to the user when the VCS of a command couldn't be fetched
+func getVCSErrorMessage(err error) string {
+	if err == service.ErrVCSNotFound {
+		return err.Error()
+	}
+
+	return "Failed to get VCS details. Please try again later. If the problem persists, contact your system administrator."
+}
+
+var errMissingProject = errors.New("Please specify the project as `<vcs alias> <org> <repo>`, as a slug such as `gh/org/repo` or as the URL of the project on CircleCI or of the repository. You can also set the default project of the channel with `/circleci set-default`.")
+
+// commandProject is the project a command is run for
+type commandProject struct {
+	VCS  *serializer.VCS
+	Org  string
+	Repo string
+	// PipelineNumber and WorkflowID are set when the project is given as the CircleCI URL of a pipeline or a workflow
+	PipelineNumber string
+	WorkflowID     string
+}
+
+// GetSlug returns the project slug in the `<vcs type>/<org>/<repo>` format
+func (p *commandProject) GetSlug() string {
+	return strings.Join([]string{p.VCS.Type, p.Org, p.Repo}, "/")
+}
+
+// getCommandProject returns the project of a command along with the arguments following it.
+// The default project of the channel is used if the arguments don't start with a project.
+// The returned error can be shown to the user.
+func getCommandProject(ctx *model.CommandArgs, args []string) (*commandProject, []string, error) {
+	project, rest, err := parseCommandProject(args)
+	if err != nil || project != nil {
+		return project, rest, err
+	}
+
+	defaultProject, err := store.GetDefaultProject(ctx.ChannelId)
+	if err != nil {
+		return nil, nil, errors.New("Failed to get the default project of the channel. Please try again later. If the problem persists, contact your system administrator.")
+	}
+	if defaultProject == nil {
+		if len(args) >= 3 {
+			// the first argument was meant to be a VCS alias
+			return nil, args, service.ErrVCSNotFound
+		}
+		return nil, args, errMissingProject
+	}
+
+	vcs, err := service.GetVCS(defaultProject.VCSAlias)
+	if err != nil {
+		return nil, nil, errors.New(getVCSErrorMessage(err))
+	}
+
+	return &commandProject{VCS: vcs, Org: defaultProject.OrgName, Repo: defaultProject.RepoName}, args, nil
+}
+
+// parseCommandProject parses the project at the start of the arguments, given either as a single project reference
+// such as `gh/org/repo` or a URL, or as the `<vcs alias> <org> <repo>` arguments.
+// nil is returned if the arguments don't start with a project.
+func parseCommandProject(args []string) (*commandProject, []string, error) {
+	// Project references are told apart from the other arguments by the slashes in slugs and URLs
+	if len(args) > 0 && strings.Contains(args[0], "/") {
+		project, err := resolveProjectReference(args[0])
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return project, args[1:], nil
+	}
+
+	if len(args) >= 3 {
+		vcs, err := service.GetVCS(args[0])
+		if err == nil {
+			return &commandProject{VCS: vcs, Org: args[1], Repo: args[2]}, args[3:], nil
+		}
+		if err != service.ErrVCSNotFound {
+			return nil, nil, errors.New(getVCSErrorMessage(err))
+		}
+	}
+
+	return nil, args, nil
+}
+
+// resolveProjectReference parses the project reference and finds its VCS.
+// Repository URLs are matched to the VCS with the same host.
+func resolveProjectReference(reference string) (*commandProject, error) {
+	vcsList, err := service.GetVCSList()
+	if err != nil {
+		return nil, errors.New(getVCSErrorMessage(err))
+	}
+
+	ref, err := serializer.ParseProjectReference(reference, vcsList)
+	if err != nil {
+		return nil, err
+	}
+
+	project := &commandProject{
+		Org:            ref.OrgName,
+		Repo:           ref.RepoName,
+		PipelineNumber: ref.PipelineNumber,
+		WorkflowID:     ref.WorkflowID,
+	}
+
+	if ref.VCS != "" {
+		if project.VCS, err = service.GetVCS(ref.VCS); err != nil {
+			return nil, errors.New(getVCSErrorMessage(err))
+		}
+
+		return project, nil
+	}
+
+	if project.VCS = serializer.FindVCSByRepoURL(vcsList, ref.RepoURL); project.VCS == nil {
+		return nil, fmt.Errorf("No VCS exists for `%s`. Use `/circleci list vcs` to see the available VCS.", ref.RepoURL)
+	}
+
+	return project, nil
+}
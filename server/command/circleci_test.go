@@ -0,0 +1,124 @@
+package command
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/config"
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/serializer"
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/store"
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/util"
+)
+
+const testEncryptionKey = "0123456789abcdef0123456789abcdef"
+
+// setupConnectedUser mocks the plugin API for a user connected to a CircleCI API returning the status for every request
+func setupConnectedUser(t *testing.T, userID string, status int) *plugintest.API {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+
+	config.SetConfig(&config.Configuration{CircleCIURL: server.URL, EncryptionKey: testEncryptionKey})
+
+	encryptedToken, err := util.EncryptVersioned([]byte(testEncryptionKey), "token")
+	require.NoError(t, err)
+
+	api := &plugintest.API{}
+	api.On("KVGet", store.CircleCIAuthTokenKey(userID)).Return([]byte(encryptedToken), nil)
+	config.Mattermost = api
+	return api
+}
+
+func TestExecuteProjectSummaryWithDefaultProject(t *testing.T) {
+	api := setupConnectedUser(t, "user1", http.StatusInternalServerError)
+	defaultProject, err := json.Marshal(&serializer.DefaultProject{VCSAlias: serializer.VCSTypeGithub, OrgName: "org", RepoName: "repo"})
+	require.NoError(t, err)
+	api.On("KVGet", "default_project_channel1").Return(defaultProject, nil)
+	api.On("LogError", "Failed to fetch project summary.", "Project", "github/org/repo", "Error", mock.Anything).Return()
+
+	response, appErr := executeProjectSummary(&model.CommandArgs{UserId: "user1", ChannelId: "channel1"})
+	require.Nil(t, appErr)
+	assert.Contains(t, response.Text, "Failed to fetch project summary from CircleCI")
+	api.AssertExpectations(t)
+}
+
+func TestParseTimeArg(t *testing.T) {
+	now := time.Date(2020, 9, 25, 15, 4, 5, 0, time.UTC)
+
+	for _, tc := range []struct {
+		value    string
+		expected time.Time
+	}{
+		{value: "2020-09-20", expected: time.Date(2020, 9, 20, 0, 0, 0, 0, time.UTC)},
+		{value: "2020-09-20T10:00:00Z", expected: time.Date(2020, 9, 20, 10, 0, 0, 0, time.UTC)},
+		{value: "12h", expected: now.Add(-12 * time.Hour)},
+		{value: "90m", expected: now.Add(-90 * time.Minute)},
+		{value: "7d", expected: now.AddDate(0, 0, -7)},
+		{value: "0d", expected: now},
+	} {
+		t.Run(tc.value, func(t *testing.T) {
+			parsed, err := parseTimeArg(tc.value, now)
+			require.NoError(t, err)
+			assert.True(t, tc.expected.Equal(parsed), "expected %s, got %s", tc.expected, parsed)
+		})
+	}
+}
+
+func TestParseTimeArgInvalid(t *testing.T) {
+	now := time.Now()
+	for _, value := range []string{"yesterday", "2020-13-01", "-7d", "-12h", "7days", ""} {
+		_, err := parseTimeArg(value, now)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestApplyRecentBuildsArgsStatuses(t *testing.T) {
+	query := &serializer.RecentBuildsQuery{}
+	err := applyRecentBuildsArgs(query, map[string][]string{"status": {"failed", "success"}}, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, []string{serializer.StatusFailure, serializer.StatusSuccess}, query.Statuses)
+
+	err = applyRecentBuildsArgs(&serializer.RecentBuildsQuery{}, map[string][]string{"status": {"failure", "broken"}}, time.Now())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "`broken`")
+	assert.Contains(t, err.Error(), serializer.StatusOnHold)
+}
+
+func TestProjectCommandsRejectExtraArgs(t *testing.T) {
+	config.SetConfig(&config.Configuration{})
+	defaultProject, err := json.Marshal(&serializer.DefaultProject{VCSAlias: serializer.VCSTypeGithub, OrgName: "org", RepoName: "repo"})
+	require.NoError(t, err)
+
+	api := &plugintest.API{}
+	api.On("KVGet", "default_project_channel1").Return(defaultProject, nil)
+	// no custom VCS
+	api.On("KVGet", mock.AnythingOfType("string")).Return(nil, nil)
+	config.Mattermost = api
+
+	ctx := &model.CommandArgs{UserId: "user1", ChannelId: "channel1"}
+	for name, execute := range map[string]func(*model.CommandArgs, ...string) (*model.CommandResponse, *model.AppError){
+		"project-insight": executeProjectSummary,
+		"environment":     executeGetAllEnvironmentVariables,
+	} {
+		t.Run(name, func(t *testing.T) {
+			response, appErr := execute(ctx, "github/org/repo", "extra")
+			require.Nil(t, appErr)
+			assert.Contains(t, response.Text, "Incorrect syntax")
+
+			// incomplete project arguments aren't run against the default project
+			response, appErr = execute(ctx, "github", "org")
+			require.Nil(t, appErr)
+			assert.Contains(t, response.Text, "Incorrect syntax")
+		})
+	}
+}
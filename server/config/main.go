@@ -2,6 +2,7 @@ package config
 
 import (
 	"errors"
+	"net/url"
 	"strings"
 
 	"github.com/mattermost/mattermost-server/v5/plugin"
@@ -13,9 +14,18 @@ const (
 
 	URLPluginBase = "/plugins/" + PluginName
 	URLStaticBase = URLPluginBase + "/static"
+	URLAPIBase    = URLPluginBase + "/api/v1"
+
+	PathWorkflowAction  = "/workflow-action"
+	PathConnectDialog   = "/connect"
+	PathBuildDialog     = "/build"
+	PathAutocompleteVCS = "/autocomplete/vcs"
 
 	HeaderMattermostUserID = "Mattermost-User-Id"
 
+	DefaultCircleCIURL    = "https://circleci.com"
+	DefaultCircleCIAppURL = "https://app.circleci.com"
+
 	BotUserName    = "circleci"
 	BotDisplayName = "CircleCI"
 	BotDescription = "Created by the CircleCI Plugin."
@@ -36,8 +46,13 @@ var (
 )
 
 type Configuration struct {
-	Secret        string `json:"Secret"`
-	EncryptionKey string `json:"EncryptionKey"`
+	Secret                string `json:"Secret"`
+	EncryptionKey         string `json:"EncryptionKey"`
+	PreviousEncryptionKey string `json:"PreviousEncryptionKey"`
+	NotifyCommitterByDM   bool   `json:"NotifyCommitterByDM"`
+	CircleCIURL           string `json:"CircleCIURL"`
+	ServiceToken          string `json:"ServiceToken"`
+	FailedStepLogLines    int    `json:"FailedStepLogLines"`
 }
 
 func GetConfig() *Configuration {
@@ -48,9 +63,47 @@ func SetConfig(c *Configuration) {
 	config.Store(c)
 }
 
+// GetEncryptionKeys returns the keys which can be used to decrypt stored values.
+// The current key comes first, followed by the previous key during the grace period after a rotation.
+func (c *Configuration) GetEncryptionKeys() [][]byte {
+	keys := [][]byte{[]byte(c.EncryptionKey)}
+	if c.PreviousEncryptionKey != "" && c.PreviousEncryptionKey != c.EncryptionKey {
+		keys = append(keys, []byte(c.PreviousEncryptionKey))
+	}
+
+	return keys
+}
+
+// GetCircleCIURL returns the URL of the CircleCI installation, which is CircleCI cloud unless a CircleCI Server URL is configured
+func (c *Configuration) GetCircleCIURL() string {
+	if c.CircleCIURL == "" {
+		return DefaultCircleCIURL
+	}
+
+	return c.CircleCIURL
+}
+
+// GetCircleCIAPIURL returns the base path of the given version of the CircleCI API, e.g. "v2"
+func (c *Configuration) GetCircleCIAPIURL(version string) string {
+	return c.GetCircleCIURL() + "/api/" + version
+}
+
+// GetCircleCIAppURL returns the URL of the CircleCI web app.
+// CircleCI cloud serves it on a separate host while CircleCI Server serves it on the installation URL.
+func (c *Configuration) GetCircleCIAppURL() string {
+	if c.CircleCIURL == "" || c.CircleCIURL == DefaultCircleCIURL {
+		return DefaultCircleCIAppURL
+	}
+
+	return c.CircleCIURL
+}
+
 // ProcessConfiguration is used for post-processing on configuration.
 func (c *Configuration) ProcessConfiguration() error {
 	c.Secret = strings.TrimSpace(c.Secret)
+	c.PreviousEncryptionKey = strings.TrimSpace(c.PreviousEncryptionKey)
+	c.CircleCIURL = strings.TrimSuffix(strings.TrimSpace(c.CircleCIURL), "/")
+	c.ServiceToken = strings.TrimSpace(c.ServiceToken)
 
 	return nil
 }
@@ -65,5 +118,15 @@ func (c *Configuration) IsValid() error {
 		return errors.New("please provide the Encryption Key")
 	}
 
+	if c.FailedStepLogLines < 0 {
+		return errors.New("the number of log lines of failed steps can't be negative")
+	}
+
+	if c.CircleCIURL != "" {
+		if u, err := url.Parse(c.CircleCIURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errors.New("please provide a valid http(s) CircleCI URL")
+		}
+	}
+
 	return nil
 }
@@ -0,0 +1,41 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/config"
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/service"
+)
+
+var autocompleteVCS = &Endpoint{
+	Path:         config.PathAutocompleteVCS,
+	Method:       http.MethodGet,
+	Execute:      handleAutocompleteVCS,
+	RequiresAuth: true,
+}
+
+func handleAutocompleteVCS(w http.ResponseWriter, r *http.Request) {
+	vcsList, err := service.GetVCSList()
+	if err != nil {
+		config.Mattermost.LogError("Failed to fetch the VCS list for autocomplete.", "Error", err.Error())
+		http.Error(w, "Failed to fetch the VCS list", http.StatusInternalServerError)
+		return
+	}
+
+	items := make([]model.AutocompleteListItem, 0, len(vcsList))
+	for _, vcs := range vcsList {
+		items = append(items, model.AutocompleteListItem{
+			Item:     vcs.Alias,
+			HelpText: fmt.Sprintf("%s - %s", vcs.Type, vcs.BaseURL),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(items); err != nil {
+		config.Mattermost.LogError("Failed to write the VCS autocomplete response.", "Error", err.Error())
+	}
+}
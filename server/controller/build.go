@@ -0,0 +1,93 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/config"
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/serializer"
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/service"
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/store"
+)
+
+var buildDialog = &Endpoint{
+	Path:         config.PathBuildDialog,
+	Method:       http.MethodPost,
+	Execute:      handleBuildDialog,
+	RequiresAuth: true,
+}
+
+func handleBuildDialog(w http.ResponseWriter, r *http.Request) {
+	request := model.SubmitDialogRequestFromJson(r.Body)
+	if request == nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if request.Cancelled {
+		return
+	}
+
+	// The user ID header is set by the Mattermost server and can be trusted, unlike the one in the request body.
+	userID := r.Header.Get(config.HeaderMattermostUserID)
+	if !config.Mattermost.HasPermissionToChannel(userID, request.ChannelId, model.PERMISSION_CREATE_POST) {
+		writeDialogResponse(w, &model.SubmitDialogResponse{Error: "You don't have the permission to post in this channel."})
+		return
+	}
+
+	authToken, err := store.GetCircleCIToken(userID)
+	if err != nil {
+		writeDialogResponse(w, &model.SubmitDialogResponse{Error: service.AuthTokenErrorMessage(err)})
+		return
+	}
+	if authToken == "" {
+		writeDialogResponse(w, &model.SubmitDialogResponse{Error: "Your CircleCI account is not connected to Mattermost. Please use `/circleci connect` to connect your CircleCI and Mattermost accounts."})
+		return
+	}
+
+	projectSlug, _ := request.Submission[service.DialogFieldProject].(string)
+	vcsType, org, repo, err := serializer.ParseProjectSlug(strings.TrimSpace(projectSlug))
+	if err != nil {
+		writeDialogResponse(w, &model.SubmitDialogResponse{
+			Errors: map[string]string{service.DialogFieldProject: "Please enter the project in the vcs/org/repo format."},
+		})
+		return
+	}
+
+	headType, _ := request.Submission[service.DialogFieldHeadType].(string)
+	head, _ := request.Submission[service.DialogFieldHead].(string)
+	if head = strings.TrimSpace(head); head == "" {
+		writeDialogResponse(w, &model.SubmitDialogResponse{
+			Errors: map[string]string{service.DialogFieldHead: "Please enter the branch or the tag to build."},
+		})
+		return
+	}
+
+	params, err := service.GetBuildDialogParameters(request.Submission, request.State)
+	if err != nil {
+		// The dialog has a field for each parameter instead of the parameters field when it has a state
+		if request.State != "" {
+			writeDialogResponse(w, &model.SubmitDialogResponse{Error: err.Error()})
+			return
+		}
+
+		writeDialogResponse(w, &model.SubmitDialogResponse{
+			Errors: map[string]string{service.DialogFieldParameters: err.Error()},
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), service.RequestDeadline)
+	defer cancel()
+
+	client := service.NewCircleCIClient(authToken)
+	if err := service.TriggerBuild(ctx, client, userID, request.ChannelId, vcsType+"/"+org+"/"+repo, headType, head, params); err != nil {
+		writeDialogResponse(w, &model.SubmitDialogResponse{Error: err.Error()})
+		return
+	}
+
+	writeDialogResponse(w, &model.SubmitDialogResponse{})
+}
@@ -0,0 +1,66 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/config"
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/service"
+)
+
+var connectDialog = &Endpoint{
+	Path:         config.PathConnectDialog,
+	Method:       http.MethodPost,
+	Execute:      handleConnectDialog,
+	RequiresAuth: true,
+}
+
+func handleConnectDialog(w http.ResponseWriter, r *http.Request) {
+	request := model.SubmitDialogRequestFromJson(r.Body)
+	if request == nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if request.Cancelled {
+		return
+	}
+
+	userID := r.Header.Get(config.HeaderMattermostUserID)
+	authToken, _ := request.Submission[service.DialogFieldAuthToken].(string)
+	authToken = strings.TrimSpace(authToken)
+
+	circleCIUser, err := service.ConnectCircleCIAccount(userID, authToken)
+	if err == service.ErrInvalidAuthToken {
+		writeDialogResponse(w, &model.SubmitDialogResponse{
+			Errors: map[string]string{
+				service.DialogFieldAuthToken: "CircleCI rejected the token. Please check that it's a valid personal API token.",
+			},
+		})
+		return
+	}
+	if err != nil {
+		config.Mattermost.LogError("Failed to connect the CircleCI account.", "UserID", userID, "Error", err.Error())
+		writeDialogResponse(w, &model.SubmitDialogResponse{
+			Error: "Failed to connect your CircleCI account. Please try again later. If the problem persists, contact your system administrator.",
+		})
+		return
+	}
+
+	config.Mattermost.SendEphemeralPost(userID, &model.Post{
+		UserId:    config.BotUserID,
+		ChannelId: request.ChannelId,
+		Message:   fmt.Sprintf("Successfully connected to CircleCI as `%s`.", circleCIUser.Login),
+	})
+
+	writeDialogResponse(w, &model.SubmitDialogResponse{})
+}
+
+func writeDialogResponse(w http.ResponseWriter, response *model.SubmitDialogResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(response)
+}
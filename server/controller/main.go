@@ -25,6 +25,11 @@ type Endpoint struct {
 // Usage: getEndpointKey(GetMetadata): GetMetadata
 var Endpoints = map[string]*Endpoint{
 	getEndpointKey(circleCIBuildFinished): circleCIBuildFinished,
+	getEndpointKey(circleCIWebhookEvent):  circleCIWebhookEvent,
+	getEndpointKey(workflowAction):        workflowAction,
+	getEndpointKey(connectDialog):         connectDialog,
+	getEndpointKey(autocompleteVCS):       autocompleteVCS,
+	getEndpointKey(buildDialog):           buildDialog,
 }
 
 // Uniquely identifies an endpoint using path and method
@@ -0,0 +1,89 @@
+package controller
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/config"
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/serializer"
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/service"
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/store"
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/util"
+)
+
+const (
+	headerCircleCISignature = "Circleci-Signature"
+	queryParamProject       = "project"
+
+	// maxWebhookEventSize is the maximum size of the webhook payload read by the plugin
+	maxWebhookEventSize = 1 << 20
+)
+
+var circleCIWebhookEvent = &Endpoint{
+	Path:         "/circleci-webhook",
+	Method:       http.MethodPost,
+	Execute:      handleCircleCIWebhookEvent,
+	RequiresAuth: false,
+}
+
+func handleCircleCIWebhookEvent(w http.ResponseWriter, r *http.Request) {
+	// The project is taken from the receiver URL, so that the signature is verified before the payload is parsed
+	vcsType, org, repo, err := serializer.ParseProjectSlug(r.URL.Query().Get(queryParamProject))
+	if err != nil {
+		http.Error(w, "invalid project", http.StatusBadRequest)
+		return
+	}
+	projectSlug := strings.Join([]string{vcsType, org, repo}, "/")
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookEventSize))
+	if err != nil {
+		config.Mattermost.LogError("Failed to read CircleCI webhook request body.", "Error", err.Error())
+		http.Error(w, "failed to read the request body", http.StatusBadRequest)
+		return
+	}
+
+	secret, err := store.GetWebhookSecret(projectSlug)
+	if err != nil {
+		http.Error(w, "failed to verify the webhook", http.StatusInternalServerError)
+		return
+	}
+
+	if err := util.VerifyWebhookSignature(secret, r.Header.Get(headerCircleCISignature), body); err != nil {
+		config.Mattermost.LogError("Received CircleCI webhook event but the signature did not match.", "Project", projectSlug, "Error", err.Error())
+		http.Error(w, "invalid signature", http.StatusForbidden)
+		return
+	}
+
+	var event serializer.CircleCIWebhookEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		config.Mattermost.LogError("Failed to decode CircleCI webhook request body.", "Error", err.Error())
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := event.Validate(); err != nil {
+		config.Mattermost.LogWarn("Received an unsupported CircleCI webhook event.", "Type", event.Type, "Error", err.Error())
+		http.Error(w, "unsupported event", http.StatusBadRequest)
+		return
+	}
+
+	// The secret is specific to the project, so it can't be used to send the events of other projects
+	if event.GetProjectSlug() != projectSlug {
+		config.Mattermost.LogWarn("Received a CircleCI webhook event for a different project.", "Project", projectSlug, "EventProject", event.GetProjectSlug())
+		http.Error(w, "project mismatch", http.StatusBadRequest)
+		return
+	}
+
+	request := event.ToWebhookRequest()
+	service.ResolvePipelineActor(&request, event.Pipeline.ID)
+
+	if err := service.SendWebhookNotifications(request); err != nil {
+		config.Mattermost.LogError("Failed to send the CircleCI webhook notifications.", "Project", projectSlug, "Error", err.Error())
+		http.Error(w, "failed to send the notifications", http.StatusInternalServerError)
+		return
+	}
+
+	returnStatusOK(w)
+}
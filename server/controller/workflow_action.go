@@ -0,0 +1,33 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/config"
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/service"
+)
+
+var workflowAction = &Endpoint{
+	Path:         config.PathWorkflowAction,
+	Method:       http.MethodPost,
+	Execute:      handleWorkflowAction,
+	RequiresAuth: true,
+}
+
+func handleWorkflowAction(w http.ResponseWriter, r *http.Request) {
+	request := model.PostActionIntegrationRequestFromJson(r.Body)
+	if request == nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// The user ID header is set by the Mattermost server and can be trusted, unlike the one in the request body.
+	request.UserId = r.Header.Get(config.HeaderMattermostUserID)
+
+	response := service.ExecuteWorkflowAction(request)
+
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(response.ToJson())
+}
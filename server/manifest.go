@@ -49,6 +49,46 @@ const manifestStr = `
         "help_text": "The AES encryption key used to encrypt stored access tokens.",
         "placeholder": "",
         "default": null
+      },
+      {
+        "key": "PreviousEncryptionKey",
+        "display_name": "Previous At Rest Encryption Key:",
+        "type": "text",
+        "help_text": "Before regenerating the At Rest Encryption Key, copy its current value here. Stored access tokens encrypted with the previous key stay readable and are encrypted again with the new key when used. Run the /circleci admin reencrypt-tokens command to migrate all the tokens at once, then clear this setting.",
+        "placeholder": "",
+        "default": null
+      },
+      {
+        "key": "NotifyCommitterByDM",
+        "display_name": "Send Failure DMs to Committers:",
+        "type": "bool",
+        "help_text": "When true, the Mattermost user mapped to the CircleCI user who triggered a failed job also receives the failure notification as a direct message.",
+        "placeholder": "",
+        "default": false
+      },
+      {
+        "key": "CircleCIURL",
+        "display_name": "CircleCI URL:",
+        "type": "text",
+        "help_text": "The URL of the CircleCI installation. Leave empty to use CircleCI cloud. Set it to the URL of your installation, for example https://circleci.example.com, to use CircleCI Server.",
+        "placeholder": "https://circleci.com",
+        "default": null
+      },
+      {
+        "key": "ServiceToken",
+        "display_name": "Service Token:",
+        "type": "text",
+        "help_text": "A CircleCI personal API token used to fetch the logs of failed jobs. When empty, the token of a user who subscribed a channel to the project is used.",
+        "placeholder": "",
+        "default": null
+      },
+      {
+        "key": "FailedStepLogLines",
+        "display_name": "Failed Step Log Lines:",
+        "type": "number",
+        "help_text": "The number of lines at the end of the output of a failed step posted in the thread of the failure notification. Set it to 0 to disable.",
+        "placeholder": "",
+        "default": 20
       }
     ]
   }
@@ -13,6 +13,8 @@ import (
 	"github.com/chetanyakan/mattermost-plugin-circleci/server/command"
 	"github.com/chetanyakan/mattermost-plugin-circleci/server/config"
 	"github.com/chetanyakan/mattermost-plugin-circleci/server/controller"
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/service"
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/store"
 	"github.com/chetanyakan/mattermost-plugin-circleci/server/util"
 )
 
@@ -31,11 +33,22 @@ func (p *Plugin) OnActivate() error {
 		return err
 	}
 
+	if err := store.MigrateLegacySubscriptions(); err != nil {
+		config.Mattermost.LogError("Failed to migrate the subscriptions.", "Error", err.Error())
+		return err
+	}
+
 	if err := p.registerCommands(); err != nil {
 		config.Mattermost.LogError(err.Error())
 		return err
 	}
 
+	service.StartBuildPoller()
+	return nil
+}
+
+func (p *Plugin) OnDeactivate() error {
+	service.StopBuildPoller()
 	return nil
 }
 
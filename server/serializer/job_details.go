@@ -0,0 +1,78 @@
+package serializer
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxLogExcerptLength keeps the excerpt well within the maximum length of a post
+const maxLogExcerptLength = 12000
+
+var ansiEscapeRegex = regexp.MustCompile(`\x1b\[[0-9;?]*[a-zA-Z]`)
+
+// JobDetails is the part of the response of the v1.1 job details API used for showing the output of failed steps.
+// See https://circleci.com/docs/api/v1/#single-job
+type JobDetails struct {
+	BuildNum int64      `json:"build_num"`
+	Steps    []*JobStep `json:"steps"`
+}
+
+type JobStep struct {
+	Name    string           `json:"name"`
+	Actions []*JobStepAction `json:"actions"`
+}
+
+type JobStepAction struct {
+	Name      string `json:"name"`
+	Status    string `json:"status"`
+	Failed    *bool  `json:"failed"`
+	OutputURL string `json:"output_url"`
+	Index     int    `json:"index"`
+}
+
+// JobStepOutput is a message of the output of a step action
+type JobStepOutput struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// GetFailedStepAction returns the first failed action of the job's steps and the name of its step, or nil if none failed
+func (d *JobDetails) GetFailedStepAction() (string, *JobStepAction) {
+	for _, step := range d.Steps {
+		for _, action := range step.Actions {
+			if action.Status == "failed" || (action.Failed != nil && *action.Failed) {
+				return step.Name, action
+			}
+		}
+	}
+
+	return "", nil
+}
+
+// GetLogExcerpt returns the last lines of the output, without terminal escape sequences
+func GetLogExcerpt(output []JobStepOutput, lineCount int) string {
+	var builder strings.Builder
+	for _, o := range output {
+		builder.WriteString(o.Message)
+	}
+
+	text := ansiEscapeRegex.ReplaceAllString(builder.String(), "")
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	if len(lines) > lineCount {
+		lines = lines[len(lines)-lineCount:]
+	}
+
+	excerpt := strings.Join(lines, "\n")
+	if len(excerpt) > maxLogExcerptLength {
+		start := len(excerpt) - maxLogExcerptLength
+		for start < len(excerpt) && !utf8.RuneStart(excerpt[start]) {
+			start++
+		}
+		excerpt = excerpt[start:]
+	}
+
+	// a code block can't be closed from inside the excerpt
+	return strings.ReplaceAll(excerpt, "```", "` ` `")
+}
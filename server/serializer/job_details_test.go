@@ -0,0 +1,73 @@
+package serializer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetLogExcerpt(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		output    []JobStepOutput
+		lineCount int
+		expected  string
+	}{
+		{
+			name:      "last lines",
+			output:    []JobStepOutput{{Message: "one\ntwo\n"}, {Message: "three\nfour\n"}},
+			lineCount: 2,
+			expected:  "three\nfour",
+		},
+		{
+			name:      "fewer lines than the count",
+			output:    []JobStepOutput{{Message: "one\ntwo"}},
+			lineCount: 5,
+			expected:  "one\ntwo",
+		},
+		{
+			name:      "message split across outputs",
+			output:    []JobStepOutput{{Message: "npm ERR! "}, {Message: "code 1\n"}},
+			lineCount: 1,
+			expected:  "npm ERR! code 1",
+		},
+		{
+			name:      "carriage returns",
+			output:    []JobStepOutput{{Message: "one\r\ntwo\r\n"}},
+			lineCount: 2,
+			expected:  "one\ntwo",
+		},
+		{
+			name:      "terminal escape sequences",
+			output:    []JobStepOutput{{Message: "\x1b[31mFAIL\x1b[0m test\n\x1b[?25h"}},
+			lineCount: 2,
+			expected:  "FAIL test",
+		},
+		{
+			name:      "code block",
+			output:    []JobStepOutput{{Message: "```\nmarkdown\n```\n"}},
+			lineCount: 3,
+			expected:  "` ` `\nmarkdown\n` ` `",
+		},
+		{
+			name:      "empty output",
+			lineCount: 3,
+			expected:  "",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, GetLogExcerpt(tc.output, tc.lineCount))
+		})
+	}
+}
+
+func TestGetLogExcerptTruncatesLongOutput(t *testing.T) {
+	line := strings.Repeat("é", 1000)
+	output := []JobStepOutput{{Message: strings.Repeat(line+"\n", 20)}}
+
+	excerpt := GetLogExcerpt(output, 20)
+	assert.LessOrEqual(t, len(excerpt), maxLogExcerptLength)
+	assert.True(t, strings.HasPrefix(excerpt, "é"), "the excerpt must start at a rune boundary")
+	assert.True(t, strings.HasSuffix(excerpt, line))
+}
@@ -0,0 +1,46 @@
+package serializer
+
+const (
+	NotifyMeAll      = "all"
+	NotifyMeFailures = "failures"
+	NotifyMeOff      = "off"
+)
+
+// NotifyMeSettings are the personal notification settings of a user
+var NotifyMeSettings = []string{
+	NotifyMeAll,
+	NotifyMeFailures,
+	NotifyMeOff,
+}
+
+// NormalizeNotifyMeSetting maps the aliases accepted by the notify-me command to a setting.
+// An empty string is returned for unknown values.
+func NormalizeNotifyMeSetting(setting string) string {
+	switch setting {
+	case "on", NotifyMeAll:
+		return NotifyMeAll
+	case "failure", NotifyMeFailures:
+		return NotifyMeFailures
+	case "none", NotifyMeOff:
+		return NotifyMeOff
+	default:
+		return ""
+	}
+}
+
+// ShouldNotifyMe checks if a user with the setting is notified of the webhook.
+// Only the final results of workflows are notified, not the ones of each of their jobs.
+func ShouldNotifyMe(setting string, r *CircleCIWebhookRequest) bool {
+	if r.GetEventSubject() != WebhookEventSubjectWorkflow {
+		return false
+	}
+
+	switch setting {
+	case NotifyMeAll:
+		return r.Status != StatusRunning && r.Status != StatusOnHold
+	case NotifyMeFailures:
+		return r.Status == StatusFailure
+	default:
+		return false
+	}
+}
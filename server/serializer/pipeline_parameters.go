@@ -0,0 +1,178 @@
+package serializer
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	ConfigParameterTypeBoolean = "boolean"
+	ConfigParameterTypeInteger = "integer"
+	ConfigParameterTypeString  = "string"
+	ConfigParameterTypeEnum    = "enum"
+)
+
+// PipelineParameters are the parameters passed to the config of a triggered pipeline
+type PipelineParameters map[string]interface{}
+
+// ConfigParameter is a pipeline parameter declared in the `parameters` section of a project's config
+type ConfigParameter struct {
+	Type    string        `yaml:"type"`
+	Default interface{}   `yaml:"default"`
+	Enum    []interface{} `yaml:"enum"`
+}
+
+// ParsePipelineParameters parses arguments in the `key=value` format.
+// The values are kept as they were typed, use ConformTo to convert them to the types declared in the project config.
+func ParsePipelineParameters(args []string) (PipelineParameters, error) {
+	params := PipelineParameters{}
+	for _, arg := range args {
+		parts := strings.SplitN(arg, "=", 2)
+		name := strings.TrimSpace(parts[0])
+		if len(parts) != 2 || name == "" {
+			return nil, errors.Errorf("invalid pipeline parameter `%s`. Parameters must be in the `name=value` format", arg)
+		}
+
+		if _, exists := params[name]; exists {
+			return nil, errors.Errorf("pipeline parameter `%s` is specified more than once", name)
+		}
+
+		params[name] = parts[1]
+	}
+
+	return params, nil
+}
+
+// GetConfigParameters returns the pipeline parameters declared in the source of a project's config
+func GetConfigParameters(configSource string) (map[string]ConfigParameter, error) {
+	config := struct {
+		Parameters map[string]ConfigParameter `yaml:"parameters"`
+	}{}
+
+	if err := yaml.Unmarshal([]byte(configSource), &config); err != nil {
+		return nil, errors.Wrap(err, "failed to parse the project config")
+	}
+
+	return config.Parameters, nil
+}
+
+// ConformTo checks that the parameters are declared in the config and converts their values to the declared types.
+// Only the values of boolean and integer parameters are converted, so `007` passed for a string parameter is sent as is.
+func (p PipelineParameters) ConformTo(configParams map[string]ConfigParameter) error {
+	for _, name := range p.Names() {
+		declared, ok := configParams[name]
+		if !ok {
+			return errors.Errorf("the pipeline parameter `%s` is not declared in the project config. Declared parameters: %s", name, getConfigParameterNames(configParams))
+		}
+
+		value := p[name]
+		switch declared.Type {
+		case ConfigParameterTypeBoolean:
+			b, ok := toBoolean(value)
+			if !ok {
+				return errors.Errorf("the pipeline parameter `%s` must be `true` or `false`", name)
+			}
+			p[name] = b
+		case ConfigParameterTypeInteger:
+			i, ok := toInteger(value)
+			if !ok {
+				return errors.Errorf("the pipeline parameter `%s` must be an integer", name)
+			}
+			p[name] = i
+		case ConfigParameterTypeString:
+			p[name] = fmt.Sprintf("%v", value)
+		case ConfigParameterTypeEnum:
+			stringValue := fmt.Sprintf("%v", value)
+			if !declared.allowsEnumValue(stringValue) {
+				return errors.Errorf("invalid value `%s` for the pipeline parameter `%s`. Allowed values: %s", stringValue, name, declared.getEnumValues())
+			}
+			p[name] = stringValue
+		}
+	}
+
+	return nil
+}
+
+// Names returns the sorted names of the parameters
+func (p PipelineParameters) Names() []string {
+	names := make([]string, 0, len(p))
+	for name := range p {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// String formats the parameters for showing them in a post
+func (p PipelineParameters) String() string {
+	var parts []string
+	for _, name := range p.Names() {
+		parts = append(parts, fmt.Sprintf("`%s=%v`", name, p[name]))
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+func (c ConfigParameter) allowsEnumValue(value string) bool {
+	for _, allowed := range c.Enum {
+		if fmt.Sprintf("%v", allowed) == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c ConfigParameter) getEnumValues() string {
+	var values []string
+	for _, allowed := range c.Enum {
+		values = append(values, fmt.Sprintf("`%v`", allowed))
+	}
+
+	return strings.Join(values, ", ")
+}
+
+func toBoolean(value interface{}) (bool, bool) {
+	switch v := value.(type) {
+	case bool:
+		return v, true
+	case string:
+		if v == "true" || v == "false" {
+			return v == "true", true
+		}
+	}
+
+	return false, false
+}
+
+func toInteger(value interface{}) (int64, bool) {
+	switch v := value.(type) {
+	case int64:
+		return v, true
+	case string:
+		i, err := strconv.ParseInt(v, 10, 64)
+		return i, err == nil
+	}
+
+	return 0, false
+}
+
+func getConfigParameterNames(configParams map[string]ConfigParameter) string {
+	if len(configParams) == 0 {
+		return "none"
+	}
+
+	var names []string
+	for name := range configParams {
+		names = append(names, fmt.Sprintf("`%s`", name))
+	}
+	sort.Strings(names)
+
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,173 @@
+package serializer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePipelineParameters(t *testing.T) {
+	params, err := ParsePipelineParameters([]string{"run_e2e=true", "retries=3", "deploy_env=staging", "message=a=b", "empty=", "version=007", "tag=+1"})
+	require.NoError(t, err)
+	assert.Equal(t, PipelineParameters{
+		"run_e2e":    "true",
+		"retries":    "3",
+		"deploy_env": "staging",
+		"message":    "a=b",
+		"empty":      "",
+		"version":    "007",
+		"tag":        "+1",
+	}, params)
+}
+
+func TestParsePipelineParametersInvalid(t *testing.T) {
+	for _, args := range [][]string{
+		{"run_e2e"},
+		{"=true"},
+		{"deploy_env=staging", "deploy_env=production"},
+	} {
+		_, err := ParsePipelineParameters(args)
+		assert.Error(t, err, args)
+	}
+}
+
+func TestPipelineParametersConformTo(t *testing.T) {
+	configParams := map[string]ConfigParameter{
+		"run_e2e":    {Type: ConfigParameterTypeBoolean},
+		"retries":    {Type: ConfigParameterTypeInteger},
+		"version":    {Type: ConfigParameterTypeString},
+		"deploy_env": {Type: ConfigParameterTypeEnum, Enum: []interface{}{"staging", "production"}},
+		"node":       {Type: ConfigParameterTypeEnum, Enum: []interface{}{12, 14}},
+	}
+
+	for _, tc := range []struct {
+		name     string
+		params   PipelineParameters
+		expected PipelineParameters
+		isValid  bool
+	}{
+		{
+			name:     "boolean",
+			params:   PipelineParameters{"run_e2e": true},
+			expected: PipelineParameters{"run_e2e": true},
+			isValid:  true,
+		},
+		{
+			name:     "boolean text",
+			params:   PipelineParameters{"run_e2e": "false"},
+			expected: PipelineParameters{"run_e2e": false},
+			isValid:  true,
+		},
+		{
+			name:   "invalid boolean",
+			params: PipelineParameters{"run_e2e": "yes"},
+		},
+		{
+			name:     "integer",
+			params:   PipelineParameters{"retries": int64(3)},
+			expected: PipelineParameters{"retries": int64(3)},
+			isValid:  true,
+		},
+		{
+			name:     "integer text",
+			params:   PipelineParameters{"retries": "3"},
+			expected: PipelineParameters{"retries": int64(3)},
+			isValid:  true,
+		},
+		{
+			name:     "integer text with leading zeros",
+			params:   PipelineParameters{"retries": "007"},
+			expected: PipelineParameters{"retries": int64(7)},
+			isValid:  true,
+		},
+		{
+			name:   "invalid integer",
+			params: PipelineParameters{"retries": "three"},
+		},
+		{
+			name:     "number for a string",
+			params:   PipelineParameters{"version": int64(2)},
+			expected: PipelineParameters{"version": "2"},
+			isValid:  true,
+		},
+		{
+			name:     "leading zeros for a string",
+			params:   PipelineParameters{"version": "007"},
+			expected: PipelineParameters{"version": "007"},
+			isValid:  true,
+		},
+		{
+			name:     "leading plus for a string",
+			params:   PipelineParameters{"version": "+1"},
+			expected: PipelineParameters{"version": "+1"},
+			isValid:  true,
+		},
+		{
+			name:     "boolean for a string",
+			params:   PipelineParameters{"version": true},
+			expected: PipelineParameters{"version": "true"},
+			isValid:  true,
+		},
+		{
+			name:     "enum",
+			params:   PipelineParameters{"deploy_env": "staging"},
+			expected: PipelineParameters{"deploy_env": "staging"},
+			isValid:  true,
+		},
+		{
+			name:     "number for a numeric enum",
+			params:   PipelineParameters{"node": "14"},
+			expected: PipelineParameters{"node": "14"},
+			isValid:  true,
+		},
+		{
+			name:   "leading zeros for a numeric enum",
+			params: PipelineParameters{"node": "014"},
+		},
+		{
+			name:   "invalid enum",
+			params: PipelineParameters{"deploy_env": "qa"},
+		},
+		{
+			name:   "undeclared",
+			params: PipelineParameters{"run_e2e": true, "unknown": "value"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.params.ConformTo(configParams)
+			if !tc.isValid {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, tc.params)
+		})
+	}
+}
+
+func TestGetConfigParameters(t *testing.T) {
+	configParams, err := GetConfigParameters(`
+version: 2.1
+parameters:
+  run_e2e:
+    type: boolean
+    default: false
+  deploy_env:
+    type: enum
+    default: staging
+    enum: [staging, production]
+workflows:
+  build:
+    jobs: [test]
+`)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]ConfigParameter{
+		"run_e2e":    {Type: ConfigParameterTypeBoolean, Default: false},
+		"deploy_env": {Type: ConfigParameterTypeEnum, Default: "staging", Enum: []interface{}{"staging", "production"}},
+	}, configParams)
+
+	_, err = GetConfigParameters("parameters: [")
+	assert.Error(t, err)
+}
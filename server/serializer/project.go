@@ -0,0 +1,30 @@
+package serializer
+
+import (
+	"strings"
+)
+
+// FollowedProject is a project followed by a user, as listed by the v1.1 API
+type FollowedProject struct {
+	VCSType  string `json:"vcs_type"`
+	Username string `json:"username"`
+	Reponame string `json:"reponame"`
+	VCSURL   string `json:"vcs_url"`
+}
+
+// GetSlug returns the project slug in the `<vcs type>/<org>/<repo>` format
+func (p *FollowedProject) GetSlug() string {
+	return strings.Join([]string{p.VCSType, p.Username, p.Reponame}, "/")
+}
+
+// DefaultProject is the project used by the commands run in a channel when they don't specify one
+type DefaultProject struct {
+	VCSAlias string `json:"vcsAlias"`
+	OrgName  string `json:"orgName"`
+	RepoName string `json:"repoName"`
+}
+
+// String returns the project in the `<vcs alias>/<org>/<repo>` format
+func (p *DefaultProject) String() string {
+	return strings.Join([]string{p.VCSAlias, p.OrgName, p.RepoName}, "/")
+}
@@ -0,0 +1,183 @@
+package serializer
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/config"
+)
+
+// ProjectReference is a project as written by a user in a command. It can be a project slug such as `github/org/repo`
+// or `gh/org/repo`, the URL of a page of the project on CircleCI, or the URL of the repository on the VCS.
+type ProjectReference struct {
+	// VCS is the VCS alias or type given in slugs and CircleCI URLs. It's empty for repository URLs.
+	VCS string
+	// RepoURL is the URL of the repository, used for finding the VCS hosting it when VCS is empty
+	RepoURL  string
+	OrgName  string
+	RepoName string
+	// PipelineNumber and WorkflowID are only set for the CircleCI URLs of a pipeline or a workflow
+	PipelineNumber string
+	WorkflowID     string
+}
+
+// ParseProjectReference parses a project slug, a CircleCI URL or a repository URL.
+// The short VCS names are expanded to their full form. vcsList is used for finding the custom VCS in CircleCI URLs.
+func ParseProjectReference(reference string, vcsList []*VCS) (*ProjectReference, error) {
+	reference = strings.TrimSpace(reference)
+
+	// SSH URLs in the scp-like syntax, such as `git@github.com:org/repo.git`
+	if !strings.Contains(reference, "://") && strings.Contains(reference, "@") && strings.Contains(reference, ":") {
+		host := GetRepoURLHost(reference)
+		repoPath := reference[strings.Index(reference, ":")+1:]
+		return parseRepoURLPath(reference, "https://"+host, getPathSegments(repoPath))
+	}
+
+	if !strings.Contains(reference, "://") {
+		vcs, org, repo, err := ParseProjectSlug(reference)
+		if err != nil {
+			return nil, errors.Errorf("invalid project `%s`. Use the `<vcs alias>/<org>/<repo>` format, or the URL of the project on CircleCI or of the repository", reference)
+		}
+
+		return &ProjectReference{VCS: vcs, OrgName: org, RepoName: repo}, nil
+	}
+
+	u, err := url.Parse(reference)
+	if err != nil || u.Host == "" {
+		return nil, errors.Errorf("invalid URL `%s`", reference)
+	}
+
+	segments := getPathSegments(u.Path)
+	host := strings.ToLower(u.Hostname())
+	if isCircleCIHost(host) {
+		return parseCircleCIURLPath(reference, segments, vcsList, !isCircleCICloudHost(host))
+	}
+
+	return parseRepoURLPath(reference, u.Scheme+"://"+u.Host, segments)
+}
+
+// parseCircleCIURLPath parses the path of a CircleCI page such as `/pipelines/github/org/repo/123/workflows/<workflow ID>`,
+// `/settings/project/github/org/repo` or `/gh/org/repo` for the legacy UI.
+func parseCircleCIURLPath(reference string, segments []string, vcsList []*VCS, isServer bool) (*ProjectReference, error) {
+	for i, segment := range segments {
+		vcs := matchCircleCIURLVCS(segment, vcsList, isServer)
+		if vcs == "" {
+			continue
+		}
+		if i+2 >= len(segments) {
+			break
+		}
+
+		ref := &ProjectReference{
+			VCS:      vcs,
+			OrgName:  segments[i+1],
+			RepoName: segments[i+2],
+		}
+
+		// the number following the project is only a pipeline number on the pipelines pages
+		rest := segments[i+3:]
+		if segments[0] == "pipelines" && len(rest) > 0 {
+			if _, err := strconv.ParseInt(rest[0], 10, 64); err == nil {
+				ref.PipelineNumber = rest[0]
+			}
+			if len(rest) > 2 && rest[1] == "workflows" {
+				ref.WorkflowID = rest[2]
+			}
+		}
+
+		return ref, nil
+	}
+
+	return nil, errors.Errorf("no project found in the CircleCI URL `%s`", reference)
+}
+
+// matchCircleCIURLVCS returns the alias of the VCS a segment of a CircleCI URL refers to, or an empty string if it isn't a VCS.
+// The segment is either the alias of a VCS or a VCS type. On CircleCI Server, a VCS type refers to the custom VCS
+// of this type if there is one, as the projects of GitHub Enterprise are shown under `github` for example.
+func matchCircleCIURLVCS(segment string, vcsList []*VCS, isServer bool) string {
+	for _, vcs := range vcsList {
+		if _, isDefault := DefaultVCSList[vcs.Alias]; !isDefault && vcs.Alias == segment {
+			return vcs.Alias
+		}
+	}
+
+	// The default VCS are matched by their type
+	vcsType := expandVCSType(segment)
+	if vcsType != VCSTypeGithub && vcsType != VCSTypeBitbucket {
+		return ""
+	}
+
+	if isServer {
+		for _, vcs := range vcsList {
+			if _, isDefault := DefaultVCSList[vcs.Alias]; !isDefault && vcs.Type == vcsType {
+				return vcs.Alias
+			}
+		}
+	}
+
+	return vcsType
+}
+
+// parseRepoURLPath parses the path of a repository URL such as `/org/repo.git` or `/org/repo/tree/main`.
+// The `/projects/<key>/repos/<repo>` paths of Bitbucket Server are supported as well.
+func parseRepoURLPath(reference, baseURL string, segments []string) (*ProjectReference, error) {
+	var org, repo string
+	switch {
+	case len(segments) >= 4 && segments[0] == "projects" && segments[2] == "repos":
+		org, repo = segments[1], segments[3]
+	case len(segments) >= 2:
+		org, repo = segments[0], segments[1]
+	default:
+		return nil, errors.Errorf("no repository found in the URL `%s`", reference)
+	}
+
+	return &ProjectReference{
+		RepoURL:  baseURL,
+		OrgName:  org,
+		RepoName: strings.TrimSuffix(repo, ".git"),
+	}, nil
+}
+
+func getPathSegments(path string) []string {
+	var segments []string
+	for _, segment := range strings.Split(path, "/") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+
+	return segments
+}
+
+// isCircleCIHost checks if the host serves CircleCI cloud or the configured CircleCI Server installation
+func isCircleCIHost(host string) bool {
+	for _, circleCIURL := range []string{
+		config.DefaultCircleCIURL,
+		config.DefaultCircleCIAppURL,
+		config.GetConfig().GetCircleCIURL(),
+	} {
+		if GetRepoURLHost(circleCIURL) == host {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isCircleCICloudHost(host string) bool {
+	return host == GetRepoURLHost(config.DefaultCircleCIURL) || host == GetRepoURLHost(config.DefaultCircleCIAppURL)
+}
+
+func expandVCSType(vcsType string) string {
+	switch vcsType {
+	case "gh":
+		return VCSTypeGithub
+	case "bb":
+		return VCSTypeBitbucket
+	default:
+		return vcsType
+	}
+}
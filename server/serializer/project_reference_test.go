@@ -0,0 +1,152 @@
+package serializer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/config"
+)
+
+func TestParseProjectReference(t *testing.T) {
+	config.SetConfig(&config.Configuration{CircleCIURL: "https://circleci.example.com"})
+
+	for _, tc := range []struct {
+		reference string
+		expected  ProjectReference
+	}{
+		{
+			reference: "github/org/repo",
+			expected:  ProjectReference{VCS: VCSTypeGithub, OrgName: "org", RepoName: "repo"},
+		},
+		{
+			reference: "gh/org/repo",
+			expected:  ProjectReference{VCS: VCSTypeGithub, OrgName: "org", RepoName: "repo"},
+		},
+		{
+			reference: "bb/org/repo",
+			expected:  ProjectReference{VCS: VCSTypeBitbucket, OrgName: "org", RepoName: "repo"},
+		},
+		{
+			reference: "ghe/org/repo",
+			expected:  ProjectReference{VCS: "ghe", OrgName: "org", RepoName: "repo"},
+		},
+		{
+			reference: "https://app.circleci.com/pipelines/github/org/repo",
+			expected:  ProjectReference{VCS: VCSTypeGithub, OrgName: "org", RepoName: "repo"},
+		},
+		{
+			reference: "https://app.circleci.com/pipelines/github/org/repo/123",
+			expected:  ProjectReference{VCS: VCSTypeGithub, OrgName: "org", RepoName: "repo", PipelineNumber: "123"},
+		},
+		{
+			reference: "https://app.circleci.com/pipelines/gh/org/repo/123/workflows/0b2d4c3e-1f2a-4b5c-8d9e-0f1a2b3c4d5e/jobs/456",
+			expected: ProjectReference{
+				VCS:            VCSTypeGithub,
+				OrgName:        "org",
+				RepoName:       "repo",
+				PipelineNumber: "123",
+				WorkflowID:     "0b2d4c3e-1f2a-4b5c-8d9e-0f1a2b3c4d5e",
+			},
+		},
+		{
+			reference: "https://app.circleci.com/settings/project/bitbucket/org/repo",
+			expected:  ProjectReference{VCS: VCSTypeBitbucket, OrgName: "org", RepoName: "repo"},
+		},
+		{
+			reference: "https://circleci.com/gh/org/repo/456",
+			expected:  ProjectReference{VCS: VCSTypeGithub, OrgName: "org", RepoName: "repo"},
+		},
+		{
+			reference: "https://circleci.example.com/pipelines/github/org/repo/7",
+			expected:  ProjectReference{VCS: VCSTypeGithub, OrgName: "org", RepoName: "repo", PipelineNumber: "7"},
+		},
+		{
+			reference: "https://github.com/org/repo",
+			expected:  ProjectReference{RepoURL: "https://github.com", OrgName: "org", RepoName: "repo"},
+		},
+		{
+			reference: "https://github.com/org/repo.git",
+			expected:  ProjectReference{RepoURL: "https://github.com", OrgName: "org", RepoName: "repo"},
+		},
+		{
+			reference: "https://github.example.com/org/repo/tree/main",
+			expected:  ProjectReference{RepoURL: "https://github.example.com", OrgName: "org", RepoName: "repo"},
+		},
+		{
+			reference: "https://bitbucket.org/org/repo/src/master/",
+			expected:  ProjectReference{RepoURL: "https://bitbucket.org", OrgName: "org", RepoName: "repo"},
+		},
+		{
+			reference: "https://bitbucket.example.com/projects/KEY/repos/repo/browse",
+			expected:  ProjectReference{RepoURL: "https://bitbucket.example.com", OrgName: "KEY", RepoName: "repo"},
+		},
+		{
+			reference: "git@github.com:org/repo.git",
+			expected:  ProjectReference{RepoURL: "https://github.com", OrgName: "org", RepoName: "repo"},
+		},
+	} {
+		t.Run(tc.reference, func(t *testing.T) {
+			ref, err := ParseProjectReference(tc.reference, nil)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, *ref)
+		})
+	}
+}
+
+func TestParseProjectReferenceInvalid(t *testing.T) {
+	config.SetConfig(&config.Configuration{})
+
+	for _, reference := range []string{
+		"org/repo",
+		"github/org/repo/extra",
+		"https://app.circleci.com/pipelines",
+		"https://github.com/org",
+	} {
+		_, err := ParseProjectReference(reference, nil)
+		assert.Error(t, err, reference)
+	}
+}
+
+func TestParseProjectReferenceCustomVCS(t *testing.T) {
+	config.SetConfig(&config.Configuration{CircleCIURL: "https://circleci.example.com"})
+
+	vcsList := []*VCS{
+		{Alias: "ghe", Type: VCSTypeGithub, BaseURL: "https://github.example.com"},
+		DefaultVCSList[VCSTypeGithub],
+		DefaultVCSList[VCSTypeBitbucket],
+	}
+
+	for _, tc := range []struct {
+		reference string
+		expected  ProjectReference
+	}{
+		{
+			reference: "https://circleci.example.com/pipelines/ghe/org/repo/7",
+			expected:  ProjectReference{VCS: "ghe", OrgName: "org", RepoName: "repo", PipelineNumber: "7"},
+		},
+		{
+			reference: "https://circleci.example.com/pipelines/github/org/repo/7",
+			expected:  ProjectReference{VCS: "ghe", OrgName: "org", RepoName: "repo", PipelineNumber: "7"},
+		},
+		{
+			reference: "https://circleci.example.com/gh/org/repo",
+			expected:  ProjectReference{VCS: "ghe", OrgName: "org", RepoName: "repo"},
+		},
+		{
+			reference: "https://circleci.example.com/pipelines/bitbucket/org/repo",
+			expected:  ProjectReference{VCS: VCSTypeBitbucket, OrgName: "org", RepoName: "repo"},
+		},
+		{
+			reference: "https://app.circleci.com/pipelines/github/org/repo",
+			expected:  ProjectReference{VCS: VCSTypeGithub, OrgName: "org", RepoName: "repo"},
+		},
+	} {
+		t.Run(tc.reference, func(t *testing.T) {
+			ref, err := ParseProjectReference(tc.reference, vcsList)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, *ref)
+		})
+	}
+}
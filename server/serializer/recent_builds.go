@@ -0,0 +1,62 @@
+package serializer
+
+import (
+	"time"
+)
+
+const (
+	DefaultRecentBuildsLimit = 10
+	MaxRecentBuildsLimit     = 50
+)
+
+// RecentBuildsQuery holds the filters of the recent-builds command
+type RecentBuildsQuery struct {
+	ProjectSlug string
+	// Workflow is the name of the workflow to list the runs of. All the workflows are listed when empty.
+	Workflow string
+	Branch   string
+	Statuses []string
+	// Since and Until bound the creation time of the builds. They are ignored when zero.
+	Since time.Time
+	Until time.Time
+	Limit int
+}
+
+// RecentBuild is a single workflow run listed by the recent-builds command
+type RecentBuild struct {
+	PipelineNumber int64
+	WorkflowID     string
+	WorkflowName   string
+	Branch         string
+	Tag            string
+	Status         string
+	TriggeredBy    string
+	CreatedAt      time.Time
+	StoppedAt      time.Time
+}
+
+// MatchStatus checks if the status of the build is one of the statuses of the query.
+// All the statuses match when the query doesn't filter on statuses.
+func (q *RecentBuildsQuery) MatchStatus(status string) bool {
+	if len(q.Statuses) == 0 {
+		return true
+	}
+
+	status = NormalizeStatus(status)
+	for _, s := range q.Statuses {
+		if NormalizeStatus(s) == status {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetDuration returns the time taken by the build, or zero if it hasn't stopped yet
+func (b *RecentBuild) GetDuration() time.Duration {
+	if b.StoppedAt.IsZero() || b.StoppedAt.Before(b.CreatedAt) {
+		return 0
+	}
+
+	return b.StoppedAt.Sub(b.CreatedAt).Round(time.Second)
+}
@@ -0,0 +1,29 @@
+package serializer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecentBuildsQueryMatchStatus(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		statuses []string
+		status   string
+		expected bool
+	}{
+		{name: "no status filter", status: "failed", expected: true},
+		{name: "same status", statuses: []string{StatusSuccess}, status: "success", expected: true},
+		{name: "CircleCI status name", statuses: []string{StatusFailure}, status: "failed", expected: true},
+		{name: "other CircleCI status name", statuses: []string{StatusFailure}, status: "error", expected: true},
+		{name: "one of the statuses", statuses: []string{StatusSuccess, StatusOnHold}, status: "needs_approval", expected: true},
+		{name: "other status", statuses: []string{StatusFailure}, status: "success"},
+		{name: "running build", statuses: []string{StatusSuccess, StatusFailure}, status: "running"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			query := &RecentBuildsQuery{Statuses: tc.statuses}
+			assert.Equal(t, tc.expected, query.MatchStatus(tc.status))
+		})
+	}
+}
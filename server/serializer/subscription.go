@@ -2,9 +2,11 @@ package serializer
 
 import (
 	"net/url"
+	"path"
 	"strings"
 
 	"github.com/pkg/errors"
+	"github.com/thoas/go-funk"
 
 	"github.com/chetanyakan/mattermost-plugin-circleci/server/util"
 )
@@ -15,6 +17,22 @@ type Subscription struct {
 	OrgName   string `json:"orgName"`
 	RepoName  string `json:"repoName"`
 	ChannelID string `json:"channelID"`
+	// CreatorID is the ID of the Mattermost user who created or last updated the subscription
+	CreatorID string `json:"creatorID,omitempty"`
+
+	Filters SubscriptionFilters `json:"filters"`
+	// GroupByWorkflow shows all the jobs of a workflow in a single post instead of creating a post per job
+	GroupByWorkflow bool `json:"groupByWorkflow,omitempty"`
+}
+
+// SubscriptionFilters restricts the webhook events that are posted for a subscription.
+// An empty filter matches every event with one of the default statuses.
+// The branches and jobs are matched with path.Match, so `*` doesn't match a `/` of a branch name.
+type SubscriptionFilters struct {
+	Branches []string `json:"branches,omitempty"`
+	Jobs     []string `json:"jobs,omitempty"`
+	Statuses []string `json:"statuses,omitempty"`
+	TagsOnly bool     `json:"tagsOnly,omitempty"`
 }
 
 // Validate checks if the subscription has valid fields
@@ -35,9 +53,83 @@ func (s *Subscription) Validate() error {
 		return errors.New("repo name cannot be empty")
 	}
 
+	return s.Filters.Validate()
+}
+
+// Validate checks if the glob patterns and statuses of the filters are valid
+func (f *SubscriptionFilters) Validate() error {
+	for _, pattern := range append(append([]string{}, f.Branches...), f.Jobs...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return errors.Wrapf(err, "invalid pattern `%s`", pattern)
+		}
+	}
+
+	for _, status := range f.Statuses {
+		if !funk.ContainsString(ValidStatuses, status) {
+			return errors.Errorf("invalid status `%s`. Valid statuses are: %s", status, strings.Join(ValidStatuses, ", "))
+		}
+	}
+
 	return nil
 }
 
+// Match checks if the webhook event passes all the filters
+func (f *SubscriptionFilters) Match(r *CircleCIWebhookRequest) bool {
+	if f.TagsOnly && r.Tag == "" {
+		return false
+	}
+
+	if len(f.Branches) > 0 && (r.Branch == "" || !matchAny(f.Branches, r.Branch)) {
+		return false
+	}
+
+	if len(f.Jobs) > 0 && !matchAny(f.Jobs, r.JobName) {
+		return false
+	}
+
+	if !funk.ContainsString(f.GetStatuses(), r.Status) {
+		return false
+	}
+
+	return true
+}
+
+// String returns the filters in a human readable format
+func (f *SubscriptionFilters) String() string {
+	var parts []string
+	if len(f.Branches) > 0 {
+		parts = append(parts, "branch: "+strings.Join(f.Branches, ", "))
+	}
+	if len(f.Jobs) > 0 {
+		parts = append(parts, "job: "+strings.Join(f.Jobs, ", "))
+	}
+	parts = append(parts, "status: "+strings.Join(f.GetStatuses(), ", "))
+	if f.TagsOnly {
+		parts = append(parts, "tags only")
+	}
+
+	return strings.Join(parts, "; ")
+}
+
+// GetStatuses returns the statuses to notify for. The default statuses are used if none were specified.
+func (f *SubscriptionFilters) GetStatuses() []string {
+	if len(f.Statuses) == 0 {
+		return DefaultStatuses
+	}
+
+	return f.Statuses
+}
+
+func matchAny(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, value); matched {
+			return true
+		}
+	}
+
+	return false
+}
+
 // GetKey returns the key against which data can be stored in a map
 func (s *Subscription) GetKey() string {
 	baseURL, _ := url.Parse(s.BaseURL)
@@ -0,0 +1,91 @@
+package serializer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubscriptionFiltersMatch(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		filters  SubscriptionFilters
+		request  CircleCIWebhookRequest
+		expected bool
+	}{
+		{
+			name:     "no filters",
+			request:  CircleCIWebhookRequest{Branch: "main", JobName: "test", Status: StatusFailure},
+			expected: true,
+		},
+		{
+			name:    "status outside the default statuses",
+			request: CircleCIWebhookRequest{Branch: "main", Status: StatusOnHold},
+		},
+		{
+			name:     "branch pattern",
+			filters:  SubscriptionFilters{Branches: []string{"main", "release/*"}},
+			request:  CircleCIWebhookRequest{Branch: "release/1.0", Status: StatusSuccess},
+			expected: true,
+		},
+		{
+			name:    "branch pattern doesn't cross slashes",
+			filters: SubscriptionFilters{Branches: []string{"release/*"}},
+			request: CircleCIWebhookRequest{Branch: "release/1.0/hotfix", Status: StatusSuccess},
+		},
+		{
+			name:    "branch filter without branch",
+			filters: SubscriptionFilters{Branches: []string{"*"}},
+			request: CircleCIWebhookRequest{Tag: "v1.0", Status: StatusSuccess},
+		},
+		{
+			name:     "job pattern",
+			filters:  SubscriptionFilters{Jobs: []string{"deploy-*"}},
+			request:  CircleCIWebhookRequest{JobName: "deploy-staging", Status: StatusSuccess},
+			expected: true,
+		},
+		{
+			name:    "other job",
+			filters: SubscriptionFilters{Jobs: []string{"deploy-*"}},
+			request: CircleCIWebhookRequest{JobName: "test", Status: StatusSuccess},
+		},
+		{
+			name:     "status",
+			filters:  SubscriptionFilters{Statuses: []string{StatusOnHold}},
+			request:  CircleCIWebhookRequest{Status: StatusOnHold},
+			expected: true,
+		},
+		{
+			name:    "other status",
+			filters: SubscriptionFilters{Statuses: []string{StatusFailure}},
+			request: CircleCIWebhookRequest{Status: StatusSuccess},
+		},
+		{
+			name:     "tags only",
+			filters:  SubscriptionFilters{TagsOnly: true},
+			request:  CircleCIWebhookRequest{Tag: "v1.0", Status: StatusSuccess},
+			expected: true,
+		},
+		{
+			name:    "tags only without tag",
+			filters: SubscriptionFilters{TagsOnly: true},
+			request: CircleCIWebhookRequest{Branch: "main", Status: StatusSuccess},
+		},
+		{
+			name:     "all filters",
+			filters:  SubscriptionFilters{Branches: []string{"main"}, Jobs: []string{"build"}, Statuses: []string{StatusFailure}},
+			request:  CircleCIWebhookRequest{Branch: "main", JobName: "build", Status: StatusFailure},
+			expected: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.filters.Match(&tc.request))
+		})
+	}
+}
+
+func TestSubscriptionFiltersValidate(t *testing.T) {
+	assert.NoError(t, (&SubscriptionFilters{Branches: []string{"release/*"}, Statuses: []string{StatusOnHold}}).Validate())
+	assert.Error(t, (&SubscriptionFilters{Branches: []string{"release/["}}).Validate())
+	assert.Error(t, (&SubscriptionFilters{Statuses: []string{"unknown"}}).Validate())
+}
@@ -56,15 +56,37 @@ func (list *Subscriptions) Remove(s Subscription) {
 	key := s.GetKey()
 	delete(list.ByChannelID[s.ChannelID], key)
 	list.ByKey[key] = funk.FilterString(list.ByKey[key], func(el string) bool {
-		return el == s.ChannelID
+		return el != s.ChannelID
 	})
 }
 
+// RemoveChannel removes all the subscriptions of a channel
+func (list *Subscriptions) RemoveChannel(channelID string) {
+	for _, s := range list.ByChannelID[channelID] {
+		list.Remove(s)
+	}
+
+	delete(list.ByChannelID, channelID)
+}
+
 // GetChannelID returns the channelID to which the message for a subscription should be posted to
 func (list *Subscriptions) GetChannelIDs(s Subscription) []string {
 	return list.ByKey[s.GetKey()]
 }
 
+// GetSubscriptions returns the subscriptions of all the channels subscribed to the same repository as the provided subscription
+func (list *Subscriptions) GetSubscriptions(s Subscription) []Subscription {
+	key := s.GetKey()
+	values := make([]Subscription, 0, len(list.ByKey[key]))
+	for _, channelID := range list.ByKey[key] {
+		if sub, found := list.ByChannelID[channelID][key]; found {
+			values = append(values, sub)
+		}
+	}
+
+	return values
+}
+
 // List returns the list for a particular channel as a formatted mattermost message
 func (list *Subscriptions) List(channelID string) []Subscription {
 	values := make([]Subscription, 0, len(list.ByChannelID[channelID]))
@@ -74,3 +96,51 @@ func (list *Subscriptions) List(channelID string) []Subscription {
 
 	return values
 }
+
+// RepoSubscriptions stores the subscriptions of all the channels to a repository by channel ID
+type RepoSubscriptions map[string]Subscription
+
+func RepoSubscriptionsFromJSON(bytes []byte) (RepoSubscriptions, error) {
+	subs := RepoSubscriptions{}
+	if len(bytes) == 0 {
+		return subs, nil
+	}
+
+	if err := json.Unmarshal(bytes, &subs); err != nil {
+		return nil, err
+	}
+
+	return subs, nil
+}
+
+// ChannelSubscriptionIndex stores the keys of the repositories a channel is subscribed to
+type ChannelSubscriptionIndex []string
+
+func ChannelSubscriptionIndexFromJSON(bytes []byte) (ChannelSubscriptionIndex, error) {
+	index := ChannelSubscriptionIndex{}
+	if len(bytes) == 0 {
+		return index, nil
+	}
+
+	if err := json.Unmarshal(bytes, &index); err != nil {
+		return nil, err
+	}
+
+	return index, nil
+}
+
+// Add adds the repository key to the index if it's not present already
+func (index ChannelSubscriptionIndex) Add(key string) ChannelSubscriptionIndex {
+	if funk.ContainsString(index, key) {
+		return index
+	}
+
+	return append(index, key)
+}
+
+// Remove removes the repository key from the index
+func (index ChannelSubscriptionIndex) Remove(key string) ChannelSubscriptionIndex {
+	return funk.FilterString(index, func(el string) bool {
+		return el != key
+	})
+}
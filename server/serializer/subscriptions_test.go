@@ -0,0 +1,25 @@
+package serializer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubscriptionsRemove(t *testing.T) {
+	list := NewSubscriptions()
+	subscription := Subscription{VCSType: VCSTypeGithub, BaseURL: "https://github.com", OrgName: "org", RepoName: "repo"}
+	for _, channelID := range []string{"channel1", "channel2", "channel3"} {
+		s := subscription
+		s.ChannelID = channelID
+		list.Add(s)
+	}
+
+	removed := subscription
+	removed.ChannelID = "channel2"
+	list.Remove(removed)
+
+	assert.Equal(t, []string{"channel1", "channel3"}, list.GetChannelIDs(subscription))
+	assert.Empty(t, list.List("channel2"))
+	assert.Len(t, list.List("channel1"), 1)
+}
@@ -0,0 +1,94 @@
+package serializer
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/thoas/go-funk"
+)
+
+// terminalWorkflowStatuses are the statuses of the workflows which have stopped running.
+// See https://circleci.com/docs/api/v2/#operation/getWorkflowById
+var terminalWorkflowStatuses = map[string]bool{
+	"success":      true,
+	"failed":       true,
+	"error":        true,
+	"canceled":     true,
+	"not_run":      true,
+	"unauthorized": true,
+}
+
+// TrackedBuild is a pipeline triggered from Mattermost whose post is updated as its workflows run
+type TrackedBuild struct {
+	PipelineID     string `json:"pipelineID"`
+	PipelineNumber int64  `json:"pipelineNumber"`
+	// State is the state of the pipeline when it was triggered
+	State       string             `json:"state"`
+	ProjectSlug string             `json:"projectSlug"`
+	ChannelID   string             `json:"channelID"`
+	PostID      string             `json:"postID"`
+	UserID      string             `json:"userID"`
+	Parameters  PipelineParameters `json:"parameters,omitempty"`
+	// CreatedAt is the time in milliseconds at which the pipeline was triggered
+	CreatedAt int64 `json:"createdAt"`
+	// WorkflowStatuses are the statuses shown in the post, by workflow ID
+	WorkflowStatuses map[string]string `json:"workflowStatuses,omitempty"`
+}
+
+// TrackedBuilds are the tracked builds by pipeline ID
+type TrackedBuilds map[string]*TrackedBuild
+
+// TrackedBuildIndex stores the IDs of the pipelines of the tracked builds
+type TrackedBuildIndex []string
+
+func TrackedBuildFromJSON(bytes []byte) (*TrackedBuild, error) {
+	if len(bytes) == 0 {
+		return nil, nil
+	}
+
+	var build *TrackedBuild
+	if err := json.Unmarshal(bytes, &build); err != nil {
+		return nil, err
+	}
+
+	return build, nil
+}
+
+func TrackedBuildIndexFromJSON(bytes []byte) (TrackedBuildIndex, error) {
+	index := TrackedBuildIndex{}
+	if len(bytes) == 0 {
+		return index, nil
+	}
+
+	if err := json.Unmarshal(bytes, &index); err != nil {
+		return nil, err
+	}
+
+	return index, nil
+}
+
+// Add adds the pipeline ID to the index if it's not present already
+func (index TrackedBuildIndex) Add(pipelineID string) TrackedBuildIndex {
+	if funk.ContainsString(index, pipelineID) {
+		return index
+	}
+
+	return append(index, pipelineID)
+}
+
+// Remove removes the pipeline IDs from the index
+func (index TrackedBuildIndex) Remove(pipelineIDs []string) TrackedBuildIndex {
+	return funk.FilterString(index, func(el string) bool {
+		return !funk.ContainsString(pipelineIDs, el)
+	})
+}
+
+// IsTimedOut checks if the build has been tracked for longer than the timeout
+func (b *TrackedBuild) IsTimedOut(now time.Time, timeout time.Duration) bool {
+	return now.Sub(time.Unix(0, b.CreatedAt*int64(time.Millisecond))) > timeout
+}
+
+// IsTerminalWorkflowStatus checks if a workflow with the status has stopped running
+func IsTerminalWorkflowStatus(status string) bool {
+	return terminalWorkflowStatuses[status]
+}
@@ -0,0 +1,56 @@
+package serializer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrackedBuildIsTimedOut(t *testing.T) {
+	createdAt := time.Date(2020, 10, 1, 12, 0, 0, 0, time.UTC)
+	build := &TrackedBuild{CreatedAt: createdAt.UnixNano() / int64(time.Millisecond)}
+
+	for _, tc := range []struct {
+		name     string
+		now      time.Time
+		expected bool
+	}{
+		{name: "just triggered", now: createdAt, expected: false},
+		{name: "before the timeout", now: createdAt.Add(time.Hour), expected: false},
+		{name: "at the timeout", now: createdAt.Add(2 * time.Hour), expected: false},
+		{name: "after the timeout", now: createdAt.Add(2*time.Hour + time.Millisecond), expected: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, build.IsTimedOut(tc.now, 2*time.Hour))
+		})
+	}
+}
+
+func TestIsTerminalWorkflowStatus(t *testing.T) {
+	for _, tc := range []struct {
+		status   string
+		expected bool
+	}{
+		{status: "success", expected: true},
+		{status: "failed", expected: true},
+		{status: "error", expected: true},
+		{status: "canceled", expected: true},
+		{status: "not_run", expected: true},
+		{status: "unauthorized", expected: true},
+		{status: "running", expected: false},
+		{status: "on_hold", expected: false},
+		{status: "failing", expected: false},
+		{status: "", expected: false},
+	} {
+		t.Run(tc.status, func(t *testing.T) {
+			assert.Equal(t, tc.expected, IsTerminalWorkflowStatus(tc.status))
+		})
+	}
+}
+
+func TestTrackedBuildIndex(t *testing.T) {
+	index := TrackedBuildIndex{}.Add("pipeline1").Add("pipeline2").Add("pipeline1")
+	assert.Equal(t, TrackedBuildIndex{"pipeline1", "pipeline2"}, index)
+	assert.Equal(t, TrackedBuildIndex{"pipeline2"}, index.Remove([]string{"pipeline1", "pipeline3"}))
+}
@@ -0,0 +1,23 @@
+package serializer
+
+import (
+	"fmt"
+
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/config"
+)
+
+// GetWorkflowRunURL returns the URL which redirects to the page of the workflow in CircleCI
+func GetWorkflowRunURL(workflowID string) string {
+	return config.GetConfig().GetCircleCIURL() + "/workflow-run/" + workflowID
+}
+
+// GetPipelineWorkflowURL returns the URL of the workflow page in the CircleCI web app.
+// projectSlug is in the `<vcs type>/<org>/<repo>` format.
+func GetPipelineWorkflowURL(projectSlug string, pipelineNumber int64, workflowID string) string {
+	return fmt.Sprintf("%s/pipelines/%s/%d/workflows/%s", config.GetConfig().GetCircleCIAppURL(), projectSlug, pipelineNumber, workflowID)
+}
+
+// GetUserTokensURL returns the URL of the page where users create their personal API tokens
+func GetUserTokensURL() string {
+	return config.GetConfig().GetCircleCIAppURL() + "/settings/user/tokens"
+}
@@ -0,0 +1,33 @@
+package serializer
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// UserMapping links the login of a user on the VCS to their Mattermost account
+type UserMapping struct {
+	// VCSType is the type of the VCS of the login, i.e. github or bitbucket. The same login can belong to different users on each.
+	VCSType  string `json:"vcsType"`
+	VCSLogin string `json:"vcsLogin"`
+	UserID   string `json:"userID"`
+}
+
+func UserMappingFromJSON(bytes []byte) (*UserMapping, error) {
+	if len(bytes) == 0 {
+		return nil, nil
+	}
+
+	mapping := &UserMapping{}
+	if err := json.Unmarshal(bytes, mapping); err != nil {
+		return nil, err
+	}
+
+	return mapping, nil
+}
+
+// NormalizeVCSLogin returns the login in the form used for looking up mappings.
+// VCS logins are case insensitive.
+func NormalizeVCSLogin(login string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(login), "@"))
+}
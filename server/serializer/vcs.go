@@ -1,5 +1,12 @@
 package serializer
 
+import (
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
 const (
 	VCSTypeGithub    = "github"
 	VCSTypeBitbucket = "bitbucket"
@@ -25,3 +32,72 @@ type VCS struct {
 	Type    string `json:"type"`
 	BaseURL string `json:"base_url"`
 }
+
+// Validate checks if the VCS has a supported type and a valid base URL
+func (v *VCS) Validate() error {
+	if v.Type != VCSTypeGithub && v.Type != VCSTypeBitbucket {
+		return errors.Errorf("invalid VCS type `%s`. Please specify one of `%s` or `%s`", v.Type, VCSTypeGithub, VCSTypeBitbucket)
+	}
+
+	if strings.TrimSpace(v.Alias) == "" || strings.ContainsAny(v.Alias, " /") {
+		return errors.Errorf("invalid VCS alias `%s`", v.Alias)
+	}
+
+	u, err := url.Parse(v.BaseURL)
+	if err != nil || u.Hostname() == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return errors.Errorf("invalid base URL `%s`. Please specify the URL of the VCS, for example `https://github.example.com`", v.BaseURL)
+	}
+
+	return nil
+}
+
+// GetHost returns the host name of the base URL of the VCS
+func (v *VCS) GetHost() string {
+	u, err := url.Parse(v.BaseURL)
+	if err != nil {
+		return ""
+	}
+
+	return strings.ToLower(u.Hostname())
+}
+
+// GetRepoURLHost returns the host name of a repository URL.
+// Both the HTTP URLs and the SSH URLs like `git@github.com:org/repo.git` are supported.
+func GetRepoURLHost(repoURL string) string {
+	if u, err := url.Parse(repoURL); err == nil && u.Host != "" {
+		return strings.ToLower(u.Hostname())
+	}
+
+	// SSH URLs in the scp-like syntax don't have a scheme
+	host := repoURL
+	if i := strings.Index(host, "@"); i >= 0 {
+		host = host[i+1:]
+	}
+	if i := strings.IndexAny(host, ":/"); i >= 0 {
+		host = host[:i]
+	}
+
+	return strings.ToLower(host)
+}
+
+// FindVCSByRepoURL returns the VCS from the list whose base URL has the same host as the repository URL.
+// nil is returned if none matches.
+func FindVCSByRepoURL(vcsList []*VCS, repoURL string) *VCS {
+	host := GetRepoURLHost(repoURL)
+	if host == "" {
+		return nil
+	}
+
+	for _, vcs := range vcsList {
+		if vcs.GetHost() == host {
+			return vcs
+		}
+	}
+
+	return nil
+}
+
+// NormalizeVCSType returns the full lowercase name of a VCS type, e.g. `github` for `GitHub` or `gh`
+func NormalizeVCSType(vcsType string) string {
+	return expandVCSType(strings.ToLower(strings.TrimSpace(vcsType)))
+}
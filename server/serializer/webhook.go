@@ -9,6 +9,51 @@ import (
 	"github.com/chetanyakan/mattermost-plugin-circleci/server/config"
 )
 
+const (
+	StatusSuccess      = "success"
+	StatusFailure      = "failure"
+	StatusCanceled     = "canceled"
+	StatusOnHold       = "on_hold"
+	StatusRunning      = "running"
+	StatusUnauthorized = "unauthorized"
+)
+
+// ValidStatuses is the list of webhook statuses which can be used in subscription filters
+var ValidStatuses = []string{
+	StatusSuccess,
+	StatusFailure,
+	StatusCanceled,
+	StatusOnHold,
+	StatusRunning,
+	StatusUnauthorized,
+}
+
+// DefaultStatuses is the list of statuses notified for subscriptions which don't filter on statuses
+var DefaultStatuses = []string{
+	StatusSuccess,
+	StatusFailure,
+}
+
+// NormalizeStatus maps the different names used by CircleCI and the orb for a status to the ones used by the plugin
+func NormalizeStatus(status string) string {
+	switch strings.ToLower(strings.TrimSpace(status)) {
+	case "failed", "error", "failing", "infrastructure_fail", "timedout", StatusFailure:
+		return StatusFailure
+	case "cancelled", StatusCanceled:
+		return StatusCanceled
+	case "needs-approval", "needs_approval", "hold", "blocked", StatusOnHold:
+		return StatusOnHold
+	case "started", "in_progress", StatusRunning:
+		return StatusRunning
+	case "unauthorized":
+		return StatusUnauthorized
+	case "succeeded", StatusSuccess:
+		return StatusSuccess
+	default:
+		return status
+	}
+}
+
 type CircleCIWebhookRequest struct {
 	Status         string `json:"status"`
 	BuildNum       string `json:"build_num"`
@@ -25,19 +70,28 @@ type CircleCIWebhookRequest struct {
 	PipelineNumber string `json:"pipeline_number"`
 	JobName        string `json:"job_name"`
 	WorkflowID     string `json:"workflow_id"`
+	WorkflowName   string `json:"workflow_name"`
+	// EventType is either "job" or "workflow". It's considered to be "job" when not specified.
+	EventType string `json:"event_type"`
+	// MattermostUsername is the username of the Mattermost user mapped to Username.
+	// It is always resolved by the plugin and never taken from the webhook payload.
+	MattermostUsername string `json:"mattermost_username,omitempty"`
+	// VCS is the VCS hosting the repository, matched by the plugin from RepoURL.
+	// It is always resolved by the plugin and never taken from the webhook payload.
+	VCS *VCS `json:"vcs,omitempty"`
 }
 
 func (r *CircleCIWebhookRequest) GetSubscription() Subscription {
-	var vcs *VCS
-	// TODO: Add support for Github Enterprise
-	if strings.HasPrefix(r.RepoURL, "git@github.com") {
-		vcs = DefaultVCSList[VCSTypeGithub]
-	} else {
+	vcs := r.VCS
+	if vcs == nil {
+		vcs = FindVCSByRepoURL([]*VCS{DefaultVCSList[VCSTypeGithub], DefaultVCSList[VCSTypeBitbucket]}, r.RepoURL)
+	}
+	if vcs == nil {
 		vcs = DefaultVCSList[VCSTypeBitbucket]
 	}
 
 	s := Subscription{
-		VCSType:  vcs.Type,
+		VCSType:  vcs.Alias,
 		BaseURL:  vcs.BaseURL,
 		OrgName:  r.OrgName,
 		RepoName: r.RepoName,
@@ -45,6 +99,24 @@ func (r *CircleCIWebhookRequest) GetSubscription() Subscription {
 	return s
 }
 
+// GetVCSType returns the type of the VCS hosting the repository, i.e. github or bitbucket
+func (r *CircleCIWebhookRequest) GetVCSType() string {
+	if r.VCS != nil {
+		return r.VCS.Type
+	}
+
+	return r.GetSubscription().VCSType
+}
+
+// GetEventSubject returns if the webhook was sent for a job or for a complete workflow
+func (r *CircleCIWebhookRequest) GetEventSubject() string {
+	if r.EventType == WebhookEventSubjectWorkflow {
+		return WebhookEventSubjectWorkflow
+	}
+
+	return WebhookEventSubjectJob
+}
+
 func (r *CircleCIWebhookRequest) getSlackAttachmentFields() []*model.SlackAttachmentField {
 	workflowText := r.PipelineNumber
 	if strings.TrimSpace(workflowText) == "" {
@@ -57,21 +129,34 @@ func (r *CircleCIWebhookRequest) getSlackAttachmentFields() []*model.SlackAttach
 			Value: r.OrgName + "/" + r.RepoName,
 			Short: false,
 		},
-		{
+	}
+
+	if r.GetEventSubject() == WebhookEventSubjectJob {
+		slackAttachmentFields = append(slackAttachmentFields, &model.SlackAttachmentField{
 			Title: "Job Number",
 			Value: fmt.Sprintf("[%s](%s)", r.BuildNum, r.BuildURL),
 			Short: true,
-		},
-		{
+		})
+	}
+
+	slackAttachmentFields = append(slackAttachmentFields, &model.SlackAttachmentField{
+		Title: "Workflow",
+		Value: fmt.Sprintf("[%s](%s)", workflowText, GetWorkflowRunURL(r.WorkflowID)),
+		Short: true,
+	})
+
+	if r.MattermostUsername != "" {
+		slackAttachmentFields = append(slackAttachmentFields, &model.SlackAttachmentField{
 			Title: "Triggered By",
-			Value: "@" + r.Username,
+			Value: "@" + r.MattermostUsername,
 			Short: true,
-		},
-		{
-			Title: "Workflow",
-			Value: fmt.Sprintf("[%s](%s)", workflowText, "https://circleci.com/workflow-run/"+r.WorkflowID),
+		})
+	} else if r.Username != "" {
+		slackAttachmentFields = append(slackAttachmentFields, &model.SlackAttachmentField{
+			Title: "Triggered By",
+			Value: r.Username,
 			Short: true,
-		},
+		})
 	}
 
 	if r.Branch != "" {
@@ -93,24 +178,43 @@ func (r *CircleCIWebhookRequest) getSlackAttachmentFields() []*model.SlackAttach
 	return slackAttachmentFields
 }
 
-func (r *CircleCIWebhookRequest) GenerateFailurePost() *model.Post {
+// GeneratePost creates the notification post for the status of the webhook.
+// nil is returned for unknown statuses.
+func (r *CircleCIWebhookRequest) GeneratePost() *model.Post {
 	if r == nil {
 		return nil
 	}
 
-	attachment := &model.SlackAttachment{
-		Color:    "#d10c20",
-		Title:    fmt.Sprintf(":red_circle: A **%s** job has failed!", r.JobName),
-		Fields:   r.getSlackAttachmentFields(),
-		ThumbURL: config.BotIconURLFailed,
+	switch r.Status {
+	case StatusFailure:
+		return r.GenerateFailurePost()
+	case StatusSuccess:
+		return r.GenerateSuccessPost()
+	case StatusCanceled:
+		return r.GenerateCanceledPost()
+	case StatusOnHold:
+		return r.GenerateOnHoldPost()
+	case StatusRunning:
+		return r.GenerateRunningPost()
+	case StatusUnauthorized:
+		return r.GenerateUnauthorizedPost()
+	default:
+		return nil
+	}
+}
+
+func (r *CircleCIWebhookRequest) GenerateFailurePost() *model.Post {
+	if r == nil {
+		return nil
 	}
 
-	post := &model.Post{
-		UserId: config.BotUserID,
+	post := r.generatePost("#d10c20", fmt.Sprintf(":red_circle: A **%s** %s has failed!", r.JobName, r.GetEventSubject()), config.BotIconURLFailed)
+
+	// Mentions in attachments don't notify the user, so the mention is added to the message
+	if r.MattermostUsername != "" {
+		post.Message = "@" + r.MattermostUsername
 	}
 
-	post.AddProp("override_icon_url", config.BotIconURLFailed)
-	model.ParseSlackAttachment(post, []*model.SlackAttachment{attachment})
 	return post
 }
 
@@ -119,18 +223,64 @@ func (r *CircleCIWebhookRequest) GenerateSuccessPost() *model.Post {
 		return nil
 	}
 
+	return r.generatePost("#41aa58", fmt.Sprintf(":tada: A **%s** %s has succeeded!", r.JobName, r.GetEventSubject()), config.BotIconURLSuccess)
+}
+
+// GenerateFixedPost creates the post replied in the thread of a failing job once it succeeds again
+func (r *CircleCIWebhookRequest) GenerateFixedPost() *model.Post {
+	if r == nil {
+		return nil
+	}
+
+	return r.generatePost("#41aa58", fmt.Sprintf(":white_check_mark: The **%s** %s is fixed!", r.JobName, r.GetEventSubject()), config.BotIconURLSuccess)
+}
+
+func (r *CircleCIWebhookRequest) GenerateCanceledPost() *model.Post {
+	if r == nil {
+		return nil
+	}
+
+	return r.generatePost("#a1a1a1", fmt.Sprintf(":no_entry_sign: A **%s** %s has been canceled.", r.JobName, r.GetEventSubject()), config.BotIconURL)
+}
+
+func (r *CircleCIWebhookRequest) GenerateOnHoldPost() *model.Post {
+	if r == nil {
+		return nil
+	}
+
+	return r.generatePost("#f5a623", fmt.Sprintf(":raised_hand: A **%s** %s is on hold and needs approval.", r.JobName, r.GetEventSubject()), config.BotIconURL)
+}
+
+func (r *CircleCIWebhookRequest) GenerateRunningPost() *model.Post {
+	if r == nil {
+		return nil
+	}
+
+	return r.generatePost("#7FC1EE", fmt.Sprintf(":runner: A **%s** %s has started.", r.JobName, r.GetEventSubject()), config.BotIconURL)
+}
+
+func (r *CircleCIWebhookRequest) GenerateUnauthorizedPost() *model.Post {
+	if r == nil {
+		return nil
+	}
+
+	return r.generatePost("#e8912d", fmt.Sprintf(":lock: A **%s** %s was not authorized to run.", r.JobName, r.GetEventSubject()), config.BotIconURL)
+}
+
+func (r *CircleCIWebhookRequest) generatePost(color, title, iconURL string) *model.Post {
 	attachment := &model.SlackAttachment{
-		Color:    "#41aa58",
-		Title:    fmt.Sprintf(":tada: A **%s** job has succeeded!", r.JobName),
+		Color:    color,
+		Title:    title,
 		Fields:   r.getSlackAttachmentFields(),
-		ThumbURL: config.BotIconURLSuccess,
+		ThumbURL: iconURL,
+		Actions:  GetWorkflowActions(r.Status, r),
 	}
 
 	post := &model.Post{
 		UserId: config.BotUserID,
 	}
 
-	post.AddProp("override_icon_url", config.BotIconURLSuccess)
+	post.AddProp("override_icon_url", iconURL)
 	model.ParseSlackAttachment(post, []*model.SlackAttachment{attachment})
 	return post
 }
@@ -0,0 +1,143 @@
+package serializer
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	EventTypeWorkflowCompleted = "workflow-completed"
+	EventTypeJobCompleted      = "job-completed"
+
+	WebhookEventSubjectJob      = "job"
+	WebhookEventSubjectWorkflow = "workflow"
+)
+
+// CircleCIWebhookEvent is the payload of the webhooks natively sent by CircleCI.
+// See https://circleci.com/docs/webhooks
+type CircleCIWebhookEvent struct {
+	ID           string                   `json:"id"`
+	Type         string                   `json:"type"`
+	HappenedAt   time.Time                `json:"happened_at"`
+	Project      CircleCIEventProject     `json:"project"`
+	Organization CircleCIEventOrg         `json:"organization"`
+	Pipeline     CircleCIEventPipeline    `json:"pipeline"`
+	Workflow     CircleCIEventWorkflow    `json:"workflow"`
+	Job          *CircleCIEventJob        `json:"job,omitempty"`
+	Webhook      CircleCIEventWebhookInfo `json:"webhook"`
+}
+
+type CircleCIEventProject struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
+type CircleCIEventOrg struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type CircleCIEventPipeline struct {
+	ID     string           `json:"id"`
+	Number int64            `json:"number"`
+	VCS    CircleCIEventVCS `json:"vcs"`
+}
+
+type CircleCIEventVCS struct {
+	ProviderName        string `json:"provider_name"`
+	OriginRepositoryURL string `json:"origin_repository_url"`
+	TargetRepositoryURL string `json:"target_repository_url"`
+	Revision            string `json:"revision"`
+	Branch              string `json:"branch"`
+	Tag                 string `json:"tag"`
+}
+
+type CircleCIEventWorkflow struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	URL    string `json:"url"`
+}
+
+type CircleCIEventJob struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Number int64  `json:"number"`
+	Status string `json:"status"`
+}
+
+type CircleCIEventWebhookInfo struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// Validate checks if the event is of a supported type and has the fields required for generating notifications
+func (e *CircleCIWebhookEvent) Validate() error {
+	if e.Type != EventTypeWorkflowCompleted && e.Type != EventTypeJobCompleted {
+		return errors.Errorf("unsupported event type `%s`", e.Type)
+	}
+
+	if e.Type == EventTypeJobCompleted && e.Job == nil {
+		return errors.New("job details missing in job-completed event")
+	}
+
+	if _, _, _, err := ParseProjectSlug(e.Project.Slug); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetProjectSlug returns the project slug in the `<vcs type>/<org>/<repo>` format
+func (e *CircleCIWebhookEvent) GetProjectSlug() string {
+	vcsType, org, repo, _ := ParseProjectSlug(e.Project.Slug)
+	return strings.Join([]string{vcsType, org, repo}, "/")
+}
+
+// ToWebhookRequest converts the event into the format used by the notification pipeline
+func (e *CircleCIWebhookEvent) ToWebhookRequest() CircleCIWebhookRequest {
+	_, org, repo, _ := ParseProjectSlug(e.Project.Slug)
+	workflowURL := GetPipelineWorkflowURL(e.Project.Slug, e.Pipeline.Number, e.Workflow.ID)
+
+	r := CircleCIWebhookRequest{
+		RepoName:       repo,
+		OrgName:        org,
+		Tag:            e.Pipeline.VCS.Tag,
+		Branch:         e.Pipeline.VCS.Branch,
+		Commit:         e.Pipeline.VCS.Revision,
+		RepoURL:        e.Pipeline.VCS.OriginRepositoryURL,
+		PipelineNumber: fmt.Sprintf("%d", e.Pipeline.Number),
+		WorkflowID:     e.Workflow.ID,
+		WorkflowName:   e.Workflow.Name,
+	}
+
+	if e.Type == EventTypeWorkflowCompleted {
+		r.EventType = WebhookEventSubjectWorkflow
+		r.Status = NormalizeStatus(e.Workflow.Status)
+		r.JobName = e.Workflow.Name
+		r.BuildURL = workflowURL
+		return r
+	}
+
+	r.EventType = WebhookEventSubjectJob
+	r.Status = NormalizeStatus(e.Job.Status)
+	r.JobName = e.Job.Name
+	r.BuildNum = fmt.Sprintf("%d", e.Job.Number)
+	r.BuildURL = fmt.Sprintf("%s/jobs/%d", workflowURL, e.Job.Number)
+	return r
+}
+
+// ParseProjectSlug splits a project slug such as `gh/org/repo` or `github/org/repo` into its parts.
+// The short VCS names are expanded to their full form.
+func ParseProjectSlug(slug string) (vcsType, org, repo string, err error) {
+	parts := strings.Split(slug, "/")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return "", "", "", errors.Errorf("invalid project slug `%s`", slug)
+	}
+
+	return expandVCSType(parts[0]), parts[1], parts[2], nil
+}
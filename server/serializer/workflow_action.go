@@ -0,0 +1,68 @@
+package serializer
+
+import (
+	"fmt"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/config"
+)
+
+const (
+	WorkflowActionRerun           = "rerun"
+	WorkflowActionRerunFromFailed = "rerun-from-failed"
+	WorkflowActionCancel          = "cancel"
+	WorkflowActionApprove         = "approve"
+
+	WorkflowActionContextAction      = "action"
+	WorkflowActionContextWorkflowID  = "workflow_id"
+	WorkflowActionContextJobName     = "job_name"
+	WorkflowActionContextProjectSlug = "project_slug"
+)
+
+// GetWorkflowActions returns the interactive buttons to show on a notification post for the status of a workflow or job
+func GetWorkflowActions(status string, r *CircleCIWebhookRequest) []*model.PostAction {
+	if r == nil || r.WorkflowID == "" {
+		return nil
+	}
+
+	switch status {
+	case StatusFailure:
+		return []*model.PostAction{
+			newWorkflowAction("Rerun workflow", WorkflowActionRerun, "primary", r),
+			newWorkflowAction("Rerun from failed", WorkflowActionRerunFromFailed, "default", r),
+		}
+	case StatusCanceled:
+		return []*model.PostAction{
+			newWorkflowAction("Rerun workflow", WorkflowActionRerun, "primary", r),
+		}
+	case StatusOnHold:
+		return []*model.PostAction{
+			newWorkflowAction("Approve hold job", WorkflowActionApprove, "good", r),
+			newWorkflowAction("Cancel workflow", WorkflowActionCancel, "danger", r),
+		}
+	case StatusRunning:
+		return []*model.PostAction{
+			newWorkflowAction("Cancel workflow", WorkflowActionCancel, "danger", r),
+		}
+	default:
+		return nil
+	}
+}
+
+func newWorkflowAction(name, action, style string, r *CircleCIWebhookRequest) *model.PostAction {
+	return &model.PostAction{
+		Name:  name,
+		Type:  model.POST_ACTION_TYPE_BUTTON,
+		Style: style,
+		Integration: &model.PostActionIntegration{
+			URL: config.URLAPIBase + config.PathWorkflowAction,
+			Context: map[string]interface{}{
+				WorkflowActionContextAction:      action,
+				WorkflowActionContextWorkflowID:  r.WorkflowID,
+				WorkflowActionContextJobName:     r.JobName,
+				WorkflowActionContextProjectSlug: fmt.Sprintf("%s/%s/%s", r.GetVCSType(), r.OrgName, r.RepoName),
+			},
+		},
+	}
+}
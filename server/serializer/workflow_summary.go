@@ -0,0 +1,212 @@
+package serializer
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/config"
+)
+
+// WorkflowSummary aggregates the job events of a workflow so that they can be shown in a single post
+type WorkflowSummary struct {
+	PostID       string                 `json:"postID"`
+	WorkflowID   string                 `json:"workflowID"`
+	WorkflowName string                 `json:"workflowName"`
+	Completed    bool                   `json:"completed"`
+	Status       string                 `json:"status"`
+	Jobs         []*WorkflowSummaryJob  `json:"jobs"`
+	LastEvent    CircleCIWebhookRequest `json:"lastEvent"`
+}
+
+type WorkflowSummaryJob struct {
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	BuildNum string `json:"buildNum"`
+	BuildURL string `json:"buildURL"`
+}
+
+func WorkflowSummaryFromJSON(bytes []byte) (*WorkflowSummary, error) {
+	summary := &WorkflowSummary{}
+	if len(bytes) == 0 {
+		return summary, nil
+	}
+
+	if err := json.Unmarshal(bytes, summary); err != nil {
+		return nil, err
+	}
+
+	return summary, nil
+}
+
+// AddEvent records a job or workflow event in the summary
+func (w *WorkflowSummary) AddEvent(r CircleCIWebhookRequest) {
+	w.WorkflowID = r.WorkflowID
+	if r.WorkflowName != "" {
+		w.WorkflowName = r.WorkflowName
+	}
+	w.LastEvent = r
+
+	if r.GetEventSubject() == WebhookEventSubjectWorkflow {
+		w.Completed = true
+		w.Status = r.Status
+		return
+	}
+
+	for _, job := range w.Jobs {
+		if job.Name == r.JobName {
+			job.Status = r.Status
+			job.BuildNum = r.BuildNum
+			job.BuildURL = r.BuildURL
+			return
+		}
+	}
+
+	w.Jobs = append(w.Jobs, &WorkflowSummaryJob{
+		Name:     r.JobName,
+		Status:   r.Status,
+		BuildNum: r.BuildNum,
+		BuildURL: r.BuildURL,
+	})
+}
+
+// GetFinishedStatus returns the status of the workflow once all its jobs have finished, or an empty string if some are still running.
+// jobStatuses are the statuses of all the jobs of the workflow by name, as the summary only knows the jobs it received an event for.
+func (w *WorkflowSummary) GetFinishedStatus(jobStatuses map[string]string) string {
+	statuses := map[string]string{}
+	for name, status := range jobStatuses {
+		statuses[name] = NormalizeStatus(status)
+	}
+
+	// The received events can be more recent than the fetched statuses
+	for _, job := range w.Jobs {
+		if IsFinishedJobStatus(job.Status) {
+			statuses[job.Name] = job.Status
+		}
+	}
+
+	if len(statuses) == 0 {
+		return ""
+	}
+
+	workflowStatus := StatusSuccess
+	for _, status := range statuses {
+		switch {
+		case !IsFinishedJobStatus(status):
+			return ""
+		case status == StatusFailure:
+			workflowStatus = StatusFailure
+		case status == StatusCanceled && workflowStatus == StatusSuccess:
+			workflowStatus = StatusCanceled
+		}
+	}
+
+	return workflowStatus
+}
+
+// IsFinishedJobStatus checks if a job with the status has stopped running
+func IsFinishedJobStatus(status string) bool {
+	switch NormalizeStatus(status) {
+	case StatusSuccess, StatusFailure, StatusCanceled, StatusUnauthorized, "not_run", "retried", "terminated-unknown":
+		return true
+	default:
+		return false
+	}
+}
+
+func (w *WorkflowSummary) hasJobsWithStatus(status string) bool {
+	for _, job := range w.Jobs {
+		if job.Status == status {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (w *WorkflowSummary) getName() string {
+	if w.WorkflowName != "" {
+		return w.WorkflowName
+	}
+
+	return w.WorkflowID
+}
+
+// GeneratePost creates the post showing the status of each job of the workflow.
+// The post is only marked as succeeded or failed once the workflow has completed.
+func (w *WorkflowSummary) GeneratePost() *model.Post {
+	attachment := &model.SlackAttachment{
+		Color:    "#7FC1EE",
+		Title:    fmt.Sprintf(":hourglass_flowing_sand: The **%s** workflow is running.", w.getName()),
+		ThumbURL: config.BotThumbnail,
+	}
+
+	switch {
+	case w.Completed && w.Status == StatusSuccess:
+		attachment.Color = "#41aa58"
+		attachment.Title = fmt.Sprintf(":tada: The **%s** workflow has succeeded!", w.getName())
+		attachment.ThumbURL = config.BotIconURLSuccess
+	case w.Completed && w.Status == StatusFailure:
+		attachment.Color = "#d10c20"
+		attachment.Title = fmt.Sprintf(":red_circle: The **%s** workflow has failed!", w.getName())
+		attachment.ThumbURL = config.BotIconURLFailed
+	case w.Completed:
+		attachment.Title = fmt.Sprintf("The **%s** workflow has completed with status `%s`.", w.getName(), w.Status)
+	case w.hasJobsWithStatus(StatusFailure):
+		attachment.Color = "#d10c20"
+		attachment.Title = fmt.Sprintf(":warning: The **%s** workflow is running and has failed jobs.", w.getName())
+	}
+
+	text := "| Job | Status |\n| :-- | :-- |\n"
+	for _, job := range w.Jobs {
+		name := job.Name
+		if job.BuildURL != "" {
+			name = fmt.Sprintf("[%s](%s)", job.Name, job.BuildURL)
+		}
+		text += fmt.Sprintf("| %s | %s %s |\n", name, GetStatusIcon(job.Status), job.Status)
+	}
+	attachment.Text = text
+
+	event := w.LastEvent
+	event.EventType = WebhookEventSubjectWorkflow
+	attachment.Fields = event.getSlackAttachmentFields()
+
+	switch {
+	case w.Completed:
+		attachment.Actions = GetWorkflowActions(w.Status, &event)
+	case w.hasJobsWithStatus(StatusOnHold):
+		attachment.Actions = GetWorkflowActions(StatusOnHold, &event)
+	default:
+		attachment.Actions = GetWorkflowActions(StatusRunning, &event)
+	}
+
+	post := &model.Post{
+		Id:     w.PostID,
+		UserId: config.BotUserID,
+	}
+
+	post.AddProp("override_icon_url", attachment.ThumbURL)
+	model.ParseSlackAttachment(post, []*model.SlackAttachment{attachment})
+	return post
+}
+
+// GetStatusIcon returns the emoji shown next to a status
+func GetStatusIcon(status string) string {
+	switch status {
+	case StatusSuccess:
+		return ":white_check_mark:"
+	case StatusFailure:
+		return ":x:"
+	case StatusCanceled:
+		return ":no_entry_sign:"
+	case StatusOnHold:
+		return ":raised_hand:"
+	case StatusRunning:
+		return ":runner:"
+	case StatusUnauthorized:
+		return ":lock:"
+	default:
+		return ":grey_question:"
+	}
+}
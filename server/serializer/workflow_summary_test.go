@@ -0,0 +1,61 @@
+package serializer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkflowSummaryGetFinishedStatus(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		jobs        []*WorkflowSummaryJob
+		jobStatuses map[string]string
+		expected    string
+	}{
+		{
+			name:        "all succeeded",
+			jobs:        []*WorkflowSummaryJob{{Name: "test", Status: StatusSuccess}},
+			jobStatuses: map[string]string{"build": "success", "test": "running"},
+			expected:    StatusSuccess,
+		},
+		{
+			name:        "running job",
+			jobs:        []*WorkflowSummaryJob{{Name: "build", Status: StatusSuccess}},
+			jobStatuses: map[string]string{"build": "success", "test": "running"},
+			expected:    "",
+		},
+		{
+			name:        "waiting for approval",
+			jobs:        []*WorkflowSummaryJob{{Name: "build", Status: StatusSuccess}},
+			jobStatuses: map[string]string{"build": "success", "hold": "on_hold", "deploy": "blocked"},
+			expected:    "",
+		},
+		{
+			name:        "failed job",
+			jobs:        []*WorkflowSummaryJob{{Name: "test", Status: StatusFailure}},
+			jobStatuses: map[string]string{"build": "success", "test": "running", "deploy": "not_run"},
+			expected:    StatusFailure,
+		},
+		{
+			name:        "timed out job",
+			jobStatuses: map[string]string{"build": "success", "test": "timedout"},
+			expected:    StatusFailure,
+		},
+		{
+			name:        "canceled job",
+			jobs:        []*WorkflowSummaryJob{{Name: "build", Status: StatusSuccess}},
+			jobStatuses: map[string]string{"build": "running", "test": "canceled"},
+			expected:    StatusCanceled,
+		},
+		{
+			name:     "no jobs",
+			expected: "",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			summary := &WorkflowSummary{Jobs: tc.jobs}
+			assert.Equal(t, tc.expected, summary.GetFinishedStatus(tc.jobStatuses))
+		})
+	}
+}
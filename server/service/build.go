@@ -0,0 +1,322 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	circleci2 "github.com/TomTucka/go-circleci/circleci"
+	"github.com/antihax/optional"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/config"
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/serializer"
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/store"
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/util"
+)
+
+const (
+	HeadTypeBranch = "branch"
+	HeadTypeTag    = "tag"
+)
+
+// ErrProjectNotFound is returned when CircleCI can't find the project, or the token can't access it
+var ErrProjectNotFound = errors.New("project not found")
+
+// TriggerPipeline triggers a pipeline of the project for the branch or tag.
+// The parameters should be checked with ConformPipelineParameters first.
+func TriggerPipeline(ctx context.Context, client *CircleCIClient, projectSlug, headType, head string, params serializer.PipelineParameters) (circleci2.PipelineCreation, error) {
+	body := map[string]interface{}{}
+	switch headType {
+	case HeadTypeBranch:
+		body[HeadTypeBranch] = head
+	case HeadTypeTag:
+		body[HeadTypeTag] = head
+	default:
+		return circleci2.PipelineCreation{}, errors.Errorf("invalid head type `%s`", headType)
+	}
+
+	if len(params) > 0 {
+		body["parameters"] = params
+	}
+
+	build, response, err := client.API.PipelineApi.TriggerPipeline(ctx, projectSlug, &circleci2.PipelineApiTriggerPipelineOpts{
+		Body: optional.NewInterface(body),
+	})
+	closeResponse(response)
+	if err != nil {
+		if response != nil && response.StatusCode == http.StatusNotFound {
+			return build, ErrProjectNotFound
+		}
+		return build, err
+	}
+
+	return build, nil
+}
+
+// ConformPipelineParameters checks the parameters against the config of the latest pipeline of the project
+// and converts their values to the declared types. The check is skipped if the config can't be fetched.
+// The returned error can be shown to the user.
+func ConformPipelineParameters(ctx context.Context, client *CircleCIClient, projectSlug, headType, head string, params serializer.PipelineParameters) error {
+	if len(params) == 0 {
+		return nil
+	}
+
+	configParams, ok := getProjectConfigParameters(ctx, client, projectSlug, headType, head)
+	if !ok {
+		return nil
+	}
+
+	return params.ConformTo(configParams)
+}
+
+// getProjectConfigParameters returns the pipeline parameters declared in the config of the latest pipeline of the project.
+// The latest pipeline of the branch is preferred. false is returned if the config couldn't be fetched.
+func getProjectConfigParameters(ctx context.Context, client *CircleCIClient, projectSlug, headType, head string) (map[string]serializer.ConfigParameter, bool) {
+	branch := ""
+	if headType == HeadTypeBranch {
+		branch = head
+	}
+
+	pipelines, err := client.ListProjectPipelines(ctx, projectSlug, branch, "")
+	if err == nil && len(pipelines.Items) == 0 && branch != "" {
+		pipelines, err = client.ListProjectPipelines(ctx, projectSlug, "", "")
+	}
+	if err != nil || len(pipelines.Items) == 0 {
+		return nil, false
+	}
+
+	pipelineConfig, err := client.GetPipelineConfig(ctx, pipelines.Items[0].Id)
+	if err != nil || pipelineConfig.Source == "" {
+		return nil, false
+	}
+
+	configParams, err := serializer.GetConfigParameters(pipelineConfig.Source)
+	if err != nil {
+		config.Mattermost.LogWarn("Failed to parse the project config for checking the pipeline parameters.", "ProjectSlug", projectSlug, "Error", err.Error())
+		return nil, false
+	}
+
+	return configParams, true
+}
+
+// TriggerBuild checks the parameters, triggers the pipeline and posts the triggered workflows in the channel.
+// The post is then updated by the build poller as the workflows run. The returned error can be shown to the user.
+func TriggerBuild(ctx context.Context, client *CircleCIClient, userID, channelID, projectSlug, headType, head string, params serializer.PipelineParameters) error {
+	if err := ConformPipelineParameters(ctx, client, projectSlug, headType, head, params); err != nil {
+		return errors.Errorf("Unable to trigger build. Error: %s", err.Error())
+	}
+
+	build, err := TriggerPipeline(ctx, client, projectSlug, headType, head, params)
+	if err != nil {
+		config.Mattermost.LogError(fmt.Sprintf(
+			"Failed to trigger build. Project: %s, head type: %s, head: %s, error: %s",
+			projectSlug,
+			headType,
+			head,
+			err.Error(),
+		))
+
+		if err == ErrProjectNotFound {
+			return errors.New("Unable to trigger build. Either the specified workflow doesn't exist or the auth token is not valid.")
+		}
+		return errors.New("Unable to trigger build.")
+	}
+
+	workflows, err := client.ListPipelineWorkflows(ctx, build.Id)
+	if err != nil {
+		config.Mattermost.LogError(fmt.Sprintf(
+			"Failed to fetch pipeline details. Pipeline ID: %s, project: %s, head type: %s, head: %s, error: %s",
+			build.Id,
+			projectSlug,
+			headType,
+			head,
+			err.Error(),
+		))
+
+		return errors.New("Successfully trigger build but failed to fetch triggered build's details. You  can still view the triggered build in CircleCI.")
+	}
+
+	trackedBuild := &serializer.TrackedBuild{
+		PipelineID:     build.Id,
+		PipelineNumber: build.Number,
+		State:          build.State,
+		ProjectSlug:    projectSlug,
+		ChannelID:      channelID,
+		UserID:         userID,
+		Parameters:     params,
+		CreatedAt:      model.GetMillis(),
+	}
+
+	post := getBuildPost(trackedBuild, workflows, false)
+	post.ChannelId = channelID
+	createdPost, err := createPostAndGet(post)
+	if err != nil {
+		config.Mattermost.LogError(fmt.Sprintf(
+			"Failed to create post for triggered build. Projet: %s, err: %s",
+			projectSlug,
+			err.Error()),
+		)
+		return nil
+	}
+
+	trackedBuild.PostID = createdPost.Id
+	trackedBuild.WorkflowStatuses = getWorkflowStatuses(workflows)
+	if err := store.SaveTrackedBuild(trackedBuild); err != nil {
+		config.Mattermost.LogError("Failed to save the triggered build for updating its post.", "PipelineID", build.Id, "Error", err.Error())
+	}
+
+	return nil
+}
+
+// getBuildStatus returns the overall status of the workflows of a pipeline, or an empty string if none has started yet
+func getBuildStatus(workflows []circleci2.Workflow1) string {
+	if len(workflows) == 0 {
+		return ""
+	}
+
+	running, onHold := false, false
+	statuses := map[string]bool{}
+	for _, workflow := range workflows {
+		switch {
+		case workflow.Status == serializer.StatusOnHold:
+			onHold = true
+		case !serializer.IsTerminalWorkflowStatus(workflow.Status):
+			running = true
+		}
+		statuses[serializer.NormalizeStatus(workflow.Status)] = true
+	}
+
+	switch {
+	case running:
+		return serializer.StatusRunning
+	case onHold:
+		return serializer.StatusOnHold
+	case statuses[serializer.StatusFailure]:
+		return serializer.StatusFailure
+	case statuses[serializer.StatusUnauthorized]:
+		return serializer.StatusUnauthorized
+	case statuses[serializer.StatusSuccess] && len(statuses) == 1:
+		return serializer.StatusSuccess
+	default:
+		return serializer.StatusCanceled
+	}
+}
+
+// isBuildCompleted checks if all the workflows of the pipeline have stopped running
+func isBuildCompleted(workflows []circleci2.Workflow1) bool {
+	if len(workflows) == 0 {
+		return false
+	}
+
+	for _, workflow := range workflows {
+		if !serializer.IsTerminalWorkflowStatus(workflow.Status) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func getWorkflowStatuses(workflows []circleci2.Workflow1) map[string]string {
+	statuses := make(map[string]string, len(workflows))
+	for _, workflow := range workflows {
+		statuses[workflow.Id] = workflow.Status
+	}
+
+	return statuses
+}
+
+// getBuildPost creates the post showing the status of each workflow of a triggered pipeline.
+// stopped is set once the post is no longer updated before the pipeline has completed.
+func getBuildPost(build *serializer.TrackedBuild, workflows []circleci2.Workflow1, stopped bool) *model.Post {
+	attachmentFields := make([]*model.SlackAttachmentField, len(workflows))
+
+	for i, workflow := range workflows {
+		attachmentFields[i] = &model.SlackAttachmentField{
+			Short: false,
+			Title: "Workflow: " + strings.Title(workflow.Name),
+			Value: fmt.Sprintf(
+				"%s `%s` [%s](%s)",
+				serializer.GetStatusIcon(serializer.NormalizeStatus(workflow.Status)),
+				workflow.Status,
+				workflow.Id,
+				serializer.GetPipelineWorkflowURL(build.ProjectSlug, build.PipelineNumber, workflow.Id),
+			),
+		}
+	}
+
+	status := getBuildStatus(workflows)
+	state := status
+	if state == "" {
+		state = build.State
+	}
+
+	attachmentFields = append(
+		attachmentFields,
+		&model.SlackAttachmentField{
+			Title: "Build Number",
+			Value: fmt.Sprintf("%d", build.PipelineNumber),
+			Short: true,
+		},
+		&model.SlackAttachmentField{
+			Title: "Build ID",
+			Value: build.PipelineID,
+			Short: true,
+		},
+		&model.SlackAttachmentField{
+			Title: "State",
+			Value: state,
+			Short: true,
+		},
+	)
+
+	if len(build.Parameters) > 0 {
+		attachmentFields = append(attachmentFields, &model.SlackAttachmentField{
+			Title: "Parameters",
+			Value: build.Parameters.String(),
+			Short: false,
+		})
+	}
+
+	attachment := util.BaseSlackAttachment()
+	attachment.Pretext = fmt.Sprintf("CircleCI build %d initiated successfully.", build.PipelineNumber)
+	attachment.Fields = attachmentFields
+
+	switch status {
+	case serializer.StatusRunning:
+		attachment.Text = fmt.Sprintf(":runner: CircleCI build %d is running.", build.PipelineNumber)
+	case serializer.StatusOnHold:
+		attachment.Color = "#f5a623"
+		attachment.Text = fmt.Sprintf(":raised_hand: CircleCI build %d is on hold and needs approval.", build.PipelineNumber)
+	case serializer.StatusSuccess:
+		attachment.Color = "#41aa58"
+		attachment.Text = fmt.Sprintf(":tada: CircleCI build %d has succeeded!", build.PipelineNumber)
+	case serializer.StatusFailure:
+		attachment.Color = "#d10c20"
+		attachment.Text = fmt.Sprintf(":red_circle: CircleCI build %d has failed!", build.PipelineNumber)
+	case serializer.StatusUnauthorized:
+		attachment.Color = "#e8912d"
+		attachment.Text = fmt.Sprintf(":lock: CircleCI build %d was not authorized to run.", build.PipelineNumber)
+	case serializer.StatusCanceled:
+		attachment.Color = "#a1a1a1"
+		attachment.Text = fmt.Sprintf(":no_entry_sign: CircleCI build %d has been canceled.", build.PipelineNumber)
+	default:
+		attachment.Text = fmt.Sprintf(":hourglass_flowing_sand: CircleCI build %d initiated successfully.", build.PipelineNumber)
+	}
+
+	if stopped {
+		attachment.Text += "\n_The status of the build is no longer updated. View it in CircleCI for the latest status._"
+	}
+
+	post := &model.Post{
+		Id:        build.PostID,
+		UserId:    config.BotUserID,
+		ChannelId: build.ChannelID,
+	}
+
+	model.ParseSlackAttachment(post, []*model.SlackAttachment{attachment})
+	return post
+}
@@ -0,0 +1,265 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/config"
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/serializer"
+)
+
+const (
+	DialogFieldProject    = "project"
+	DialogFieldHeadType   = "head_type"
+	DialogFieldHead       = "head"
+	DialogFieldParameters = "parameters"
+	// DialogFieldParameterPrefix prefixes the names of the fields of the parameters declared in the project config
+	DialogFieldParameterPrefix = "param_"
+
+	// dialogDisplayNameMaxLength is the maximum length of the display name of a dialog element
+	dialogDisplayNameMaxLength = 24
+)
+
+// OpenBuildDialog opens the dialog for triggering a build.
+// When projectSlug is empty, the user picks one of their followed projects. Otherwise, the dialog has a field
+// for each pipeline parameter declared in the config of the project.
+func OpenBuildDialog(triggerID, authToken, projectSlug string) error {
+	client := NewCircleCIClient(authToken)
+	ctx, cancel := context.WithTimeout(context.Background(), RequestDeadline)
+	defer cancel()
+
+	elements := []model.DialogElement{
+		getBuildDialogProjectElement(authToken, projectSlug),
+		{
+			DisplayName: "Build Against",
+			Name:        DialogFieldHeadType,
+			Type:        "select",
+			Default:     HeadTypeBranch,
+			Options: []*model.PostActionOptions{
+				{Text: "Branch", Value: HeadTypeBranch},
+				{Text: "Tag", Value: HeadTypeTag},
+			},
+		},
+		{
+			DisplayName: "Branch or Tag",
+			Name:        DialogFieldHead,
+			Type:        "text",
+			Placeholder: "main",
+		},
+	}
+
+	var configParams map[string]serializer.ConfigParameter
+	if projectSlug != "" {
+		configParams, _ = getProjectConfigParameters(ctx, client, projectSlug, "", "")
+	}
+
+	state := ""
+	if len(configParams) > 0 {
+		paramTypes := map[string]string{}
+		for _, name := range getSortedConfigParameterNames(configParams) {
+			elements = append(elements, getConfigParameterElement(name, configParams[name]))
+			paramTypes[name] = configParams[name].Type
+		}
+
+		// The declared types are kept in the state of the dialog for converting the submitted values
+		b, _ := json.Marshal(paramTypes)
+		state = string(b)
+	} else {
+		elements = append(elements, model.DialogElement{
+			DisplayName: "Pipeline Parameters",
+			Name:        DialogFieldParameters,
+			Type:        "textarea",
+			Optional:    true,
+			Placeholder: "deploy_env=staging\nrun_e2e=true",
+			HelpText:    "One name=value pair per line. The value can contain spaces and is converted to the type declared in the project config.",
+		})
+	}
+
+	dialog := model.OpenDialogRequest{
+		TriggerId: triggerID,
+		URL:       config.URLAPIBase + config.PathBuildDialog,
+		Dialog: model.Dialog{
+			CallbackId:  "build",
+			Title:       "Trigger a CircleCI build",
+			IconURL:     config.BotIconURL,
+			SubmitLabel: "Build",
+			Elements:    elements,
+			State:       state,
+		},
+	}
+
+	if appErr := config.Mattermost.OpenInteractiveDialog(dialog); appErr != nil {
+		config.Mattermost.LogError("Failed to open the build dialog.", "Error", appErr.Error())
+		return errors.New(appErr.Error())
+	}
+
+	return nil
+}
+
+func getBuildDialogProjectElement(authToken, projectSlug string) model.DialogElement {
+	element := model.DialogElement{
+		DisplayName: "Project",
+		Name:        DialogFieldProject,
+		Type:        "text",
+		Placeholder: "github/org/repo",
+		HelpText:    "The project slug in the vcs/org/repo format",
+		Default:     projectSlug,
+	}
+
+	if projectSlug != "" {
+		return element
+	}
+
+	projects, err := ListFollowedProjects(authToken)
+	if err != nil {
+		config.Mattermost.LogWarn("Failed to list the followed projects for the build dialog.", "Error", err.Error())
+		return element
+	}
+	if len(projects) == 0 {
+		return element
+	}
+
+	element.Type = "select"
+	element.Placeholder = "Select a followed project"
+	element.HelpText = ""
+	for _, project := range projects {
+		element.Options = append(element.Options, &model.PostActionOptions{
+			Text:  fmt.Sprintf("%s/%s (%s)", project.Username, project.Reponame, project.VCSType),
+			Value: project.GetSlug(),
+		})
+	}
+
+	return element
+}
+
+func getConfigParameterElement(name string, param serializer.ConfigParameter) model.DialogElement {
+	displayName := name
+	if len(displayName) > dialogDisplayNameMaxLength {
+		displayName = displayName[:dialogDisplayNameMaxLength-3] + "..."
+	}
+
+	element := model.DialogElement{
+		DisplayName: displayName,
+		Name:        DialogFieldParameterPrefix + name,
+		Type:        "text",
+		Optional:    true,
+		HelpText:    fmt.Sprintf("Pipeline parameter %s of type %s", name, param.Type),
+	}
+	if param.Default != nil {
+		element.Default = fmt.Sprintf("%v", param.Default)
+	}
+
+	switch param.Type {
+	case serializer.ConfigParameterTypeBoolean:
+		element.Type = "bool"
+		element.Placeholder = name
+	case serializer.ConfigParameterTypeInteger:
+		element.SubType = "number"
+	case serializer.ConfigParameterTypeEnum:
+		element.Type = "select"
+		for _, value := range param.Enum {
+			option := fmt.Sprintf("%v", value)
+			element.Options = append(element.Options, &model.PostActionOptions{Text: option, Value: option})
+		}
+	}
+
+	return element
+}
+
+// GetBuildDialogParameters returns the pipeline parameters entered in the build dialog.
+// The values of the parameter fields are converted to the types kept in the state of the dialog.
+// Empty fields are skipped, so the defaults of the project config are used for them.
+func GetBuildDialogParameters(submission map[string]interface{}, state string) (serializer.PipelineParameters, error) {
+	paramTypes := map[string]string{}
+	if state != "" {
+		if err := json.Unmarshal([]byte(state), &paramTypes); err != nil {
+			return nil, errors.Wrap(err, "failed to read the pipeline parameter types of the build dialog")
+		}
+	}
+
+	params := serializer.PipelineParameters{}
+	if text, _ := submission[DialogFieldParameters].(string); strings.TrimSpace(text) != "" {
+		// Each line is a parameter, so that the values can contain spaces
+		var lines []string
+		for _, line := range strings.Split(text, "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				lines = append(lines, line)
+			}
+		}
+
+		parsed, err := serializer.ParsePipelineParameters(lines)
+		if err != nil {
+			return nil, err
+		}
+		params = parsed
+	}
+
+	for field, value := range submission {
+		if !strings.HasPrefix(field, DialogFieldParameterPrefix) {
+			continue
+		}
+
+		if value == nil || value == "" {
+			continue
+		}
+
+		name := strings.TrimPrefix(field, DialogFieldParameterPrefix)
+		paramValue, err := getDialogParameterValue(name, paramTypes[name], value)
+		if err != nil {
+			return nil, err
+		}
+		params[name] = paramValue
+	}
+
+	return params, nil
+}
+
+// getDialogParameterValue converts the value of a parameter field to the declared type of the parameter.
+// Only the values of the bool and number fields are converted, text is sent as is.
+func getDialogParameterValue(name, paramType string, value interface{}) (interface{}, error) {
+	switch paramType {
+	case serializer.ConfigParameterTypeBoolean:
+		if b, ok := value.(bool); ok {
+			return b, nil
+		}
+		if s, ok := value.(string); ok && (s == "true" || s == "false") {
+			return s == "true", nil
+		}
+		return nil, errors.Errorf("the pipeline parameter `%s` must be `true` or `false`", name)
+	case serializer.ConfigParameterTypeInteger:
+		switch v := value.(type) {
+		case float64:
+			if v == math.Trunc(v) && v >= math.MinInt64 && v < math.MaxInt64 {
+				return int64(v), nil
+			}
+		case string:
+			if i, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64); err == nil {
+				return i, nil
+			}
+		}
+		return nil, errors.Errorf("the pipeline parameter `%s` must be an integer", name)
+	}
+
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	return nil, errors.Errorf("the pipeline parameter `%s` must be text", name)
+}
+
+func getSortedConfigParameterNames(configParams map[string]serializer.ConfigParameter) []string {
+	names := make([]string, 0, len(configParams))
+	for name := range configParams {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/serializer"
+)
+
+func TestGetBuildDialogParameters(t *testing.T) {
+	state := `{"run_e2e":"boolean","retries":"integer","version":"string","deploy_env":"enum"}`
+
+	for _, tc := range []struct {
+		name       string
+		submission map[string]interface{}
+		state      string
+		expected   serializer.PipelineParameters
+		isValid    bool
+	}{
+		{
+			name: "declared types",
+			submission: map[string]interface{}{
+				DialogFieldParameterPrefix + "run_e2e":    true,
+				DialogFieldParameterPrefix + "retries":    float64(3),
+				DialogFieldParameterPrefix + "version":    "0042",
+				DialogFieldParameterPrefix + "deploy_env": "staging",
+			},
+			state: state,
+			expected: serializer.PipelineParameters{
+				"run_e2e":    true,
+				"retries":    int64(3),
+				"version":    "0042",
+				"deploy_env": "staging",
+			},
+			isValid: true,
+		},
+		{
+			name: "text values",
+			submission: map[string]interface{}{
+				DialogFieldParameterPrefix + "run_e2e": "false",
+				DialogFieldParameterPrefix + "retries": "007",
+				DialogFieldParameterPrefix + "version": "+1",
+			},
+			state: state,
+			expected: serializer.PipelineParameters{
+				"run_e2e": false,
+				"retries": int64(7),
+				"version": "+1",
+			},
+			isValid: true,
+		},
+		{
+			name: "empty fields",
+			submission: map[string]interface{}{
+				DialogFieldParameterPrefix + "retries": nil,
+				DialogFieldParameterPrefix + "version": "",
+			},
+			state:    state,
+			expected: serializer.PipelineParameters{},
+			isValid:  true,
+		},
+		{
+			name: "parameters field",
+			submission: map[string]interface{}{
+				DialogFieldParameters: "version=0042\nmessage=a b\n",
+			},
+			expected: serializer.PipelineParameters{
+				"version": "0042",
+				"message": "a b",
+			},
+			isValid: true,
+		},
+		{
+			name:       "fractional number",
+			submission: map[string]interface{}{DialogFieldParameterPrefix + "retries": 1.5},
+			state:      state,
+		},
+		{
+			name:       "invalid integer text",
+			submission: map[string]interface{}{DialogFieldParameterPrefix + "retries": "three"},
+			state:      state,
+		},
+		{
+			name:       "invalid boolean",
+			submission: map[string]interface{}{DialogFieldParameterPrefix + "run_e2e": "yes"},
+			state:      state,
+		},
+		{
+			name:       "number for a string",
+			submission: map[string]interface{}{DialogFieldParameterPrefix + "version": float64(2)},
+			state:      state,
+		},
+		{
+			name:       "invalid state",
+			submission: map[string]interface{}{DialogFieldParameterPrefix + "version": "2"},
+			state:      "{",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			params, err := GetBuildDialogParameters(tc.submission, tc.state)
+			if !tc.isValid {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, params)
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/config"
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/serializer"
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/store"
+)
+
+const (
+	buildPollInterval = 15 * time.Second
+	// buildTrackingTimeout is the time after which the post of a build which hasn't completed is no longer updated
+	buildTrackingTimeout = 2 * time.Hour
+
+	buildPollerLockKey = "build_poller_lock"
+	// buildPollerLockExpiry outlasts a poll, so that the lock is only taken over from a node which went down while polling
+	buildPollerLockExpiry = 2 * 60
+	// buildPollDeadline bounds a poll below the lock expiry. The builds which couldn't be polled in time are polled on the next tick.
+	buildPollDeadline = 90 * time.Second
+)
+
+var buildPoller struct {
+	sync.Mutex
+	stop chan struct{}
+	done chan struct{}
+}
+
+// buildPollerLockValue identifies the lock held by this node
+var buildPollerLockValue = model.NewId()
+
+// StartBuildPoller starts updating the posts of the triggered builds in the background.
+// Every node of the cluster runs the poller, but the builds are only polled by the node holding the lock.
+func StartBuildPoller() {
+	buildPoller.Lock()
+	defer buildPoller.Unlock()
+
+	if buildPoller.stop != nil {
+		return
+	}
+
+	stop, done := make(chan struct{}), make(chan struct{})
+	buildPoller.stop, buildPoller.done = stop, done
+
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(buildPollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				pollTrackedBuilds()
+			}
+		}
+	}()
+}
+
+// StopBuildPoller stops the poller and waits for the running poll to finish
+func StopBuildPoller() {
+	buildPoller.Lock()
+	defer buildPoller.Unlock()
+
+	if buildPoller.stop == nil {
+		return
+	}
+
+	close(buildPoller.stop)
+	<-buildPoller.done
+	buildPoller.stop, buildPoller.done = nil, nil
+}
+
+func pollTrackedBuilds() {
+	builds, err := store.GetTrackedBuilds()
+	if err != nil || len(builds) == 0 {
+		return
+	}
+
+	locked, err := store.TryLock(buildPollerLockKey, buildPollerLockValue, buildPollerLockExpiry)
+	if err != nil {
+		config.Mattermost.LogError("Failed to acquire the build poller lock.", "Error", err.Error())
+		return
+	}
+	if !locked {
+		return
+	}
+	defer func() {
+		if err := store.Unlock(buildPollerLockKey, buildPollerLockValue); err != nil {
+			config.Mattermost.LogError("Failed to release the build poller lock.", "Error", err.Error())
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), buildPollDeadline)
+	defer cancel()
+
+	var finished []string
+	for pipelineID, build := range builds {
+		if ctx.Err() != nil {
+			break
+		}
+
+		if updateTrackedBuild(ctx, build) {
+			finished = append(finished, pipelineID)
+		}
+	}
+
+	if len(finished) > 0 {
+		if err := store.DeleteTrackedBuilds(finished); err != nil {
+			config.Mattermost.LogError("Failed to remove the finished builds.", "Error", err.Error())
+		}
+	}
+}
+
+// updateTrackedBuild updates the post of the build if the status of its workflows has changed.
+// Returns true once the build should no longer be tracked.
+func updateTrackedBuild(pollCtx context.Context, build *serializer.TrackedBuild) bool {
+	timedOut := build.IsTimedOut(time.Now(), buildTrackingTimeout)
+
+	authToken, err := store.GetCircleCIToken(build.UserID)
+	if err != nil || authToken == "" {
+		config.Mattermost.LogWarn("Stopped updating the build as the user who triggered it is no longer connected.", "PipelineID", build.PipelineID, "UserID", build.UserID)
+		return true
+	}
+
+	ctx, cancel := context.WithTimeout(pollCtx, RequestDeadline)
+	defer cancel()
+
+	workflows, err := NewCircleCIClient(authToken).RefreshPipelineWorkflows(ctx, build.PipelineID)
+	if err != nil {
+		if pollCtx.Err() != nil {
+			// the poll ran out of time, the build is polled again on the next tick
+			return false
+		}
+
+		config.Mattermost.LogError("Failed to fetch the workflows of the triggered build.", "PipelineID", build.PipelineID, "Error", err.Error())
+		return timedOut
+	}
+
+	completed := isBuildCompleted(workflows)
+	statuses := getWorkflowStatuses(workflows)
+	if !timedOut && !completed && workflowStatusesEqual(statuses, build.WorkflowStatuses) {
+		return false
+	}
+
+	if _, appErr := config.Mattermost.UpdatePost(getBuildPost(build, workflows, timedOut && !completed)); appErr != nil {
+		config.Mattermost.LogError("Failed to update the post of the triggered build.", "PipelineID", build.PipelineID, "PostID", build.PostID, "Error", appErr.Error())
+		// the post has most likely been deleted
+		return true
+	}
+
+	if completed || timedOut {
+		return true
+	}
+
+	build.WorkflowStatuses = statuses
+	if err := store.SaveTrackedBuild(build); err != nil {
+		config.Mattermost.LogError("Failed to save the status of the triggered build.", "PipelineID", build.PipelineID, "Error", err.Error())
+	}
+
+	return false
+}
+
+func workflowStatusesEqual(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for id, status := range a {
+		if b[id] != status {
+			return false
+		}
+	}
+
+	return true
+}
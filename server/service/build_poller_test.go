@@ -0,0 +1,61 @@
+package service
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/config"
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/serializer"
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/store"
+)
+
+func TestPollTrackedBuilds(t *testing.T) {
+	build := &serializer.TrackedBuild{PipelineID: "pipeline1", UserID: "user1", CreatedAt: model.GetMillis()}
+	data, err := json.Marshal(build)
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		name   string
+		locked bool
+	}{
+		{name: "lock held by another node", locked: false},
+		{name: "lock acquired", locked: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			api := &plugintest.API{}
+			config.Mattermost = api
+
+			api.On("KVGet", "tracked_build_index").Return([]byte(`["pipeline1"]`), nil)
+			api.On("KVGet", "tracked_build_pipeline1").Return(data, nil)
+			api.On("KVSetWithOptions", buildPollerLockKey, []byte(buildPollerLockValue), model.PluginKVSetOptions{
+				Atomic:          true,
+				ExpireInSeconds: buildPollerLockExpiry,
+			}).Return(tc.locked, nil)
+
+			if tc.locked {
+				// the user who triggered the build is no longer connected, so the build stops being tracked
+				api.On("KVGet", store.CircleCIAuthTokenKey("user1")).Return(nil, nil)
+				api.On("LogWarn", mock.Anything, "PipelineID", "pipeline1", "UserID", "user1").Return()
+				api.On("KVSetWithOptions", "tracked_build_index", []byte(nil), model.PluginKVSetOptions{
+					Atomic:   true,
+					OldValue: []byte(`["pipeline1"]`),
+				}).Return(true, nil)
+				api.On("KVDelete", "tracked_build_pipeline1").Return(nil)
+				api.On("KVCompareAndDelete", buildPollerLockKey, []byte(buildPollerLockValue)).Return(true, nil)
+			}
+
+			pollTrackedBuilds()
+
+			api.AssertExpectations(t)
+			if !tc.locked {
+				api.AssertNotCalled(t, "KVGet", store.CircleCIAuthTokenKey("user1"))
+				api.AssertNotCalled(t, "KVCompareAndDelete", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// apiCacheTTL is kept short as the statuses of pipelines and workflows change while they run
+	apiCacheTTL = 30 * time.Second
+	// apiCacheMaxEntries bounds the memory used by the cache. Expired entries are dropped once it's reached.
+	apiCacheMaxEntries = 5000
+)
+
+type apiCacheEntry struct {
+	value     interface{}
+	expiresAt time.Time
+}
+
+// apiCache keeps the objects fetched from CircleCI for a short time,
+// so that the commands fetching the same users, pipelines and workflows don't request them again.
+type apiCache struct {
+	sync.Mutex
+	entries map[string]apiCacheEntry
+}
+
+var circleCIAPICache = &apiCache{
+	entries: map[string]apiCacheEntry{},
+}
+
+func (c *apiCache) get(key string) (interface{}, bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	if time.Now().After(entry.expiresAt) {
+		delete(c.entries, key)
+		return nil, false
+	}
+
+	return entry.value, true
+}
+
+func (c *apiCache) set(key string, value interface{}) {
+	c.Lock()
+	defer c.Unlock()
+
+	now := time.Now()
+	if len(c.entries) >= apiCacheMaxEntries {
+		for k, entry := range c.entries {
+			if now.After(entry.expiresAt) {
+				delete(c.entries, k)
+			}
+		}
+	}
+
+	// still full of live entries, so the new one is not worth evicting them for
+	if len(c.entries) >= apiCacheMaxEntries {
+		return
+	}
+
+	c.entries[key] = apiCacheEntry{
+		value:     value,
+		expiresAt: now.Add(apiCacheTTL),
+	}
+}
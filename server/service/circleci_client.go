@@ -0,0 +1,244 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	circleci2 "github.com/TomTucka/go-circleci/circleci"
+	"github.com/antihax/optional"
+
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/util"
+)
+
+const (
+	// MaxConcurrentRequests is the number of requests a single command makes to CircleCI at the same time
+	MaxConcurrentRequests = 5
+	// RequestDeadline bounds the time spent on the CircleCI requests of a single command
+	RequestDeadline = 30 * time.Second
+)
+
+// CircleCIClient wraps the CircleCI API client of a user.
+// The users, pipelines and workflows it fetches are cached for a short time. The cache keys include a hash
+// of the token, so that the objects fetched with the token of a user are never returned to another one.
+type CircleCIClient struct {
+	API            *circleci2.APIClient
+	cacheKeyPrefix string
+}
+
+func NewCircleCIClient(authToken string) *CircleCIClient {
+	return &CircleCIClient{
+		API:            util.GetCircleciClient(authToken),
+		cacheKeyPrefix: util.GetKeyHash(authToken) + "/",
+	}
+}
+
+// GetCurrentUser returns the CircleCI user the token belongs to. ErrInvalidAuthToken is returned if CircleCI rejects the token.
+func (c *CircleCIClient) GetCurrentUser(ctx context.Context) (circleci2.User, error) {
+	user, resp, err := c.API.UserApi.GetCurrentUser(ctx)
+	closeResponse(resp)
+	if err != nil && isUnauthorizedResponse(resp) {
+		return user, ErrInvalidAuthToken
+	}
+
+	return user, err
+}
+
+// ListCollaborations returns the organizations the user has access to
+func (c *CircleCIClient) ListCollaborations(ctx context.Context) ([]circleci2.Collaboration, error) {
+	collaborations, resp, err := c.API.UserApi.GetCollaborations(ctx)
+	closeResponse(resp)
+	return collaborations, err
+}
+
+func (c *CircleCIClient) GetPipeline(ctx context.Context, pipelineID string) (circleci2.Pipeline, error) {
+	key := c.cacheKeyPrefix + "pipeline/" + pipelineID
+	if cached, ok := circleCIAPICache.get(key); ok {
+		return cached.(circleci2.Pipeline), nil
+	}
+
+	pipeline, resp, err := c.API.PipelineApi.GetPipelineById(ctx, pipelineID)
+	closeResponse(resp)
+	if err != nil {
+		return pipeline, err
+	}
+
+	circleCIAPICache.set(key, pipeline)
+	return pipeline, nil
+}
+
+func (c *CircleCIClient) GetPipelineByNumber(ctx context.Context, projectSlug, pipelineNumber string) (circleci2.Pipeline, error) {
+	key := c.cacheKeyPrefix + "pipeline/" + projectSlug + "/" + pipelineNumber
+	if cached, ok := circleCIAPICache.get(key); ok {
+		return cached.(circleci2.Pipeline), nil
+	}
+
+	pipeline, resp, err := c.API.PipelineApi.GetPipelineByNumber(ctx, projectSlug, pipelineNumber)
+	closeResponse(resp)
+	if err != nil {
+		return pipeline, err
+	}
+
+	circleCIAPICache.set(key, pipeline)
+	return pipeline, nil
+}
+
+func (c *CircleCIClient) GetWorkflow(ctx context.Context, workflowID string) (circleci2.Workflow, error) {
+	key := c.cacheKeyPrefix + "workflow/" + workflowID
+	if cached, ok := circleCIAPICache.get(key); ok {
+		return cached.(circleci2.Workflow), nil
+	}
+
+	workflow, resp, err := c.API.WorkflowApi.GetWorkflowById(ctx, workflowID)
+	closeResponse(resp)
+	if err != nil {
+		return workflow, err
+	}
+
+	circleCIAPICache.set(key, workflow)
+	return workflow, nil
+}
+
+// ListProjectPipelines returns a page of the pipelines of the project, newest first
+func (c *CircleCIClient) ListProjectPipelines(ctx context.Context, projectSlug, branch, pageToken string) (circleci2.PipelineListResponse, error) {
+	opts := &circleci2.PipelineApiListPipelinesForProjectOpts{}
+	if branch != "" {
+		opts.Branch = optional.NewString(branch)
+	}
+	if pageToken != "" {
+		opts.PageToken = optional.NewString(pageToken)
+	}
+
+	pipelines, resp, err := c.API.PipelineApi.ListPipelinesForProject(ctx, projectSlug, opts)
+	closeResponse(resp)
+	return pipelines, err
+}
+
+// ListPipelineWorkflows returns all the workflows of the pipeline
+func (c *CircleCIClient) ListPipelineWorkflows(ctx context.Context, pipelineID string) ([]circleci2.Workflow1, error) {
+	key := c.cacheKeyPrefix + "pipeline-workflows/" + pipelineID
+	if cached, ok := circleCIAPICache.get(key); ok {
+		return cached.([]circleci2.Workflow1), nil
+	}
+
+	return c.RefreshPipelineWorkflows(ctx, pipelineID)
+}
+
+// RefreshPipelineWorkflows fetches the workflows of the pipeline bypassing the cache, for seeing their latest status
+func (c *CircleCIClient) RefreshPipelineWorkflows(ctx context.Context, pipelineID string) ([]circleci2.Workflow1, error) {
+	var workflows []circleci2.Workflow1
+	pageToken := ""
+	for {
+		opts := &circleci2.PipelineApiListWorkflowsByPipelineIdOpts{}
+		if pageToken != "" {
+			opts.PageToken = optional.NewString(pageToken)
+		}
+
+		page, resp, err := c.API.PipelineApi.ListWorkflowsByPipelineId(ctx, pipelineID, opts)
+		closeResponse(resp)
+		if err != nil {
+			return nil, err
+		}
+
+		workflows = append(workflows, page.Items...)
+		if page.NextPageToken == "" || len(page.Items) == 0 {
+			break
+		}
+		pageToken = page.NextPageToken
+	}
+
+	circleCIAPICache.set(c.cacheKeyPrefix+"pipeline-workflows/"+pipelineID, workflows)
+	return workflows, nil
+}
+
+// GetPipelineConfig returns the config of the pipeline, which is cached as it doesn't change
+func (c *CircleCIClient) GetPipelineConfig(ctx context.Context, pipelineID string) (circleci2.PipelineConfig, error) {
+	key := c.cacheKeyPrefix + "pipeline-config/" + pipelineID
+	if cached, ok := circleCIAPICache.get(key); ok {
+		return cached.(circleci2.PipelineConfig), nil
+	}
+
+	pipelineConfig, resp, err := c.API.PipelineApi.GetPipelineConfigById(ctx, pipelineID)
+	closeResponse(resp)
+	if err != nil {
+		return pipelineConfig, err
+	}
+
+	circleCIAPICache.set(key, pipelineConfig)
+	return pipelineConfig, nil
+}
+
+// ListWorkflowJobs returns the jobs of the workflow. They are not cached as they're mostly fetched to see their latest status.
+func (c *CircleCIClient) ListWorkflowJobs(ctx context.Context, workflowID string) ([]circleci2.Job, error) {
+	jobs, resp, err := c.API.WorkflowApi.ListWorkflowJobs(ctx, workflowID)
+	closeResponse(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return jobs.Items, nil
+}
+
+// GetProjectWorkflowMetrics returns the metrics of the workflows of the project
+func (c *CircleCIClient) GetProjectWorkflowMetrics(ctx context.Context, projectSlug string) ([]circleci2.InlineResponse200Items, error) {
+	metrics, resp, err := c.API.InsightsApi.GetProjectWorkflowMetrics(ctx, projectSlug, nil)
+	closeResponse(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return metrics.Items, nil
+}
+
+// ListProjectWorkflowRuns returns the runs of the workflow of the project between the start and the end
+func (c *CircleCIClient) ListProjectWorkflowRuns(ctx context.Context, projectSlug, workflowName string, start, end time.Time) ([]circleci2.InlineResponse2001Items, error) {
+	runs, resp, err := c.API.InsightsApi.GetProjectWorkflowRuns(ctx, projectSlug, workflowName, start, end, nil)
+	closeResponse(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return runs.Items, nil
+}
+
+// ListEnvVars returns the masked environment variables of the project
+func (c *CircleCIClient) ListEnvVars(ctx context.Context, projectSlug string) ([]circleci2.EnvironmentVariablePair1, error) {
+	envVars, resp, err := c.API.ProjectApi.ListEnvVars(ctx, projectSlug)
+	closeResponse(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return envVars.Items, nil
+}
+
+// RerunWorkflow reruns the workflow, optionally only from its failed jobs
+func (c *CircleCIClient) RerunWorkflow(ctx context.Context, workflowID string, fromFailed bool) error {
+	_, resp, err := c.API.WorkflowApi.RerunWorkflow(ctx, workflowID, &circleci2.WorkflowApiRerunWorkflowOpts{
+		Body: optional.NewInterface(circleci2.RerunWorkflowParameters{FromFailed: fromFailed}),
+	})
+	closeResponse(resp)
+	return err
+}
+
+func (c *CircleCIClient) CancelWorkflow(ctx context.Context, workflowID string) error {
+	_, resp, err := c.API.WorkflowApi.CancelWorkflow(ctx, workflowID)
+	closeResponse(resp)
+	return err
+}
+
+func (c *CircleCIClient) ApproveJob(ctx context.Context, workflowID, approvalRequestID string) error {
+	_, resp, err := c.API.WorkflowApi.ApprovePendingApprovalJobById(ctx, approvalRequestID, workflowID)
+	closeResponse(resp)
+	return err
+}
+
+func isUnauthorizedResponse(resp *http.Response) bool {
+	return resp != nil && (resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden)
+}
+
+func closeResponse(resp *http.Response) {
+	if resp != nil {
+		resp.Body.Close()
+	}
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/config"
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/serializer"
+)
+
+const (
+	v1RequestTimeout = 15 * time.Second
+	// maxV1ResponseSize bounds the responses read from CircleCI, such as the output of a step, as they need to be decoded at once
+	maxV1ResponseSize = 10 * 1024 * 1024
+)
+
+// v1HTTPClient is used for the v1.1 API, which isn't covered by the generated v2 client
+var v1HTTPClient = &http.Client{Timeout: v1RequestTimeout}
+
+// ListFollowedProjects returns the projects followed by the owner of the token
+func ListFollowedProjects(authToken string) ([]*serializer.FollowedProject, error) {
+	var projects []*serializer.FollowedProject
+	if err := getJSON(config.GetConfig().GetCircleCIAPIURL("v1.1")+"/projects", authToken, &projects); err != nil {
+		return nil, errors.Wrap(err, "failed to list the followed projects")
+	}
+
+	return projects, nil
+}
+
+// getJSON decodes the JSON response of a GET request. The token is only sent when it isn't empty.
+func getJSON(rawURL, authToken string, v interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Accept", "application/json")
+	if authToken != "" {
+		req.Header.Set("Circle-Token", authToken)
+	}
+
+	resp, err := v1HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	return json.NewDecoder(io.LimitReader(resp.Body, maxV1ResponseSize)).Decode(v)
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/config"
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/serializer"
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/store"
+)
+
+// postFailedStepExcerpts replies to the failure posts with the end of the output of the failed step of the job.
+// The failure posts can be the workflow summary posts. No reply is posted in the channels for which no token is available.
+func postFailedStepExcerpts(circleCIWebhook *serializer.CircleCIWebhookRequest, subscriptions []serializer.Subscription, failurePosts []*model.Post) {
+	lineCount := config.GetConfig().FailedStepLogLines
+	if lineCount <= 0 || len(failurePosts) == 0 || circleCIWebhook.GetEventSubject() != serializer.WebhookEventSubjectJob {
+		return
+	}
+
+	// The output is only fetched once per token, as a token can be used for several channels
+	messages := map[string]string{}
+	for _, failurePost := range failurePosts {
+		authToken := getJobLogToken(subscriptions, failurePost.ChannelId)
+		if authToken == "" {
+			continue
+		}
+
+		message, ok := messages[authToken]
+		if !ok {
+			message = getFailedStepMessage(authToken, circleCIWebhook, lineCount)
+			messages[authToken] = message
+		}
+		if message == "" {
+			continue
+		}
+
+		rootID := failurePost.RootId
+		if rootID == "" {
+			rootID = failurePost.Id
+		}
+
+		_ = createPost(&model.Post{
+			UserId:    config.BotUserID,
+			ChannelId: failurePost.ChannelId,
+			RootId:    rootID,
+			Message:   message,
+		})
+	}
+}
+
+// getFailedStepMessage returns the message showing the excerpt of the output of the failed step,
+// or an empty string if it can't be fetched with the token.
+func getFailedStepMessage(authToken string, circleCIWebhook *serializer.CircleCIWebhookRequest, lineCount int) string {
+	stepName, output, err := getFailedStepOutput(authToken, circleCIWebhook)
+	if err != nil {
+		config.Mattermost.LogWarn("Failed to fetch the output of the failed step.", "Error", err.Error(), "BuildNum", circleCIWebhook.BuildNum)
+		return ""
+	}
+	if output == nil {
+		return ""
+	}
+
+	excerpt := serializer.GetLogExcerpt(output, lineCount)
+	if excerpt == "" {
+		return ""
+	}
+
+	return fmt.Sprintf("Output of the failed step **%s**:\n```\n%s\n```", stepName, excerpt)
+}
+
+// getJobLogToken returns the configured service token, or the token of the user who subscribed the channel to the project.
+// The token of a user is only used for the channels they subscribed, so that the logs they can access aren't posted in other channels.
+func getJobLogToken(subscriptions []serializer.Subscription, channelID string) string {
+	if serviceToken := config.GetConfig().ServiceToken; serviceToken != "" {
+		return serviceToken
+	}
+
+	for _, s := range subscriptions {
+		if s.ChannelID != channelID || s.CreatorID == "" {
+			continue
+		}
+
+		if authToken, err := store.GetCircleCIToken(s.CreatorID); err == nil && authToken != "" {
+			return authToken
+		}
+	}
+
+	return ""
+}
+
+// getFailedStepOutput fetches the job details through the v1.1 API and returns the name and the output of its failed step.
+// A nil output is returned if no step has failed.
+func getFailedStepOutput(authToken string, circleCIWebhook *serializer.CircleCIWebhookRequest) (string, []serializer.JobStepOutput, error) {
+	buildNum, err := strconv.ParseInt(circleCIWebhook.BuildNum, 10, 64)
+	if err != nil {
+		return "", nil, errors.Errorf("invalid job number `%s`", circleCIWebhook.BuildNum)
+	}
+
+	jobURL := fmt.Sprintf(
+		"%s/project/%s/%s/%s/%d",
+		config.GetConfig().GetCircleCIAPIURL("v1.1"),
+		circleCIWebhook.GetVCSType(),
+		url.PathEscape(circleCIWebhook.OrgName),
+		url.PathEscape(circleCIWebhook.RepoName),
+		buildNum,
+	)
+
+	details := &serializer.JobDetails{}
+	if err := getJSON(jobURL, authToken, details); err != nil {
+		return "", nil, errors.Wrap(err, "failed to get the job details")
+	}
+
+	stepName, action := details.GetFailedStepAction()
+	if action == nil || action.OutputURL == "" {
+		return "", nil, nil
+	}
+
+	// The output URL is pre-signed, so the token must not be sent to it
+	var output []serializer.JobStepOutput
+	if err := getJSON(action.OutputURL, "", &output); err != nil {
+		return "", nil, errors.Wrap(err, "failed to get the step output")
+	}
+
+	return stepName, output, nil
+}
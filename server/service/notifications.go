@@ -2,7 +2,6 @@ package service
 
 import (
 	"github.com/mattermost/mattermost-server/v5/model"
-	"github.com/pkg/errors"
 
 	"github.com/chetanyakan/mattermost-plugin-circleci/server/config"
 	"github.com/chetanyakan/mattermost-plugin-circleci/server/serializer"
@@ -10,42 +9,109 @@ import (
 )
 
 func SendWebhookNotifications(circleCIWebhook serializer.CircleCIWebhookRequest) error {
-	b, err := config.Mattermost.KVGet(store.SubscriptionsKey)
+	circleCIWebhook.Status = serializer.NormalizeStatus(circleCIWebhook.Status)
+	circleCIWebhook.VCS = GetWebhookVCS(&circleCIWebhook)
+
+	mappedUser := GetMappedUser(circleCIWebhook.GetVCSType(), circleCIWebhook.Username)
+	circleCIWebhook.MattermostUsername = ""
+	notifiedByDM := false
+	if mappedUser != nil {
+		circleCIWebhook.MattermostUsername = mappedUser.Username
+		notifiedByDM = sendPersonalNotification(mappedUser.Id, &circleCIWebhook)
+	}
+
+	subscriptions, err := GetRepoSubscriptions(circleCIWebhook.GetSubscription())
 	if err != nil {
-		config.Mattermost.LogError("failed to get the list of subscriptions", "Error", err.Error())
 		return err
 	}
 
-	subscriptions, appErr := serializer.SubscriptionsFromJSON(b)
-	if appErr != nil {
-		config.Mattermost.LogError("failed to deserialize the list of subscriptions", "Error", appErr.Error())
-		return err
+	var channelIDs []string
+	var matchedSubscriptions []serializer.Subscription
+	var failurePosts []*model.Post
+	for _, s := range subscriptions {
+		if !s.Filters.Match(&circleCIWebhook) {
+			// The failure threads are closed even if the channel isn't notified of the success itself
+			if circleCIWebhook.Status == serializer.StatusSuccess {
+				closeFailureThread(s.ChannelID, &circleCIWebhook)
+			}
+			continue
+		}
+		matchedSubscriptions = append(matchedSubscriptions, s)
+
+		if s.GroupByWorkflow && circleCIWebhook.WorkflowID != "" {
+			summaryPostID, err := postWorkflowSummary(s.ChannelID, circleCIWebhook)
+			if err != nil {
+				config.Mattermost.LogError("Failed to post the workflow summary in the channel.", "Error", err.Error(), "ChannelID", s.ChannelID)
+			}
+			if summaryPostID != "" && circleCIWebhook.Status == serializer.StatusFailure {
+				failurePosts = append(failurePosts, &model.Post{Id: summaryPostID, ChannelId: s.ChannelID})
+			}
+			continue
+		}
+
+		channelIDs = append(channelIDs, s.ChannelID)
 	}
 
-	channelIDs := subscriptions.GetChannelIDs(circleCIWebhook.GetSubscription())
-	if len(channelIDs) == 0 {
-		config.Mattermost.LogWarn("Received CircleCI Webhook request, but it is not subscribed to any channels")
-		return nil
+	if len(matchedSubscriptions) > 0 && !notifiedByDM && mappedUser != nil && circleCIWebhook.Status == serializer.StatusFailure && config.GetConfig().NotifyCommitterByDM {
+		if post := circleCIWebhook.GenerateFailurePost(); post != nil {
+			_ = sendDirectMessage(mappedUser.Id, post)
+		}
 	}
 
-	var post *model.Post
-	if circleCIWebhook.Status == "failure" {
-		post = circleCIWebhook.GenerateFailurePost()
-	} else if circleCIWebhook.Status == "success" {
-		post = circleCIWebhook.GenerateSuccessPost()
+	failurePosts = append(failurePosts, postChannelNotifications(&circleCIWebhook, channelIDs)...)
+
+	// fetching the logs takes a few requests to CircleCI, so the webhook doesn't wait for it
+	go postFailedStepExcerpts(&circleCIWebhook, matchedSubscriptions, failurePosts)
+
+	return nil
+}
+
+// postChannelNotifications posts the notification in the channels and returns the created failure posts
+func postChannelNotifications(circleCIWebhook *serializer.CircleCIWebhookRequest, channelIDs []string) []*model.Post {
+	if len(channelIDs) == 0 {
+		config.Mattermost.LogDebug("Received CircleCI Webhook request, but there are no channels to create a post in")
+		return nil
 	}
 
+	post := circleCIWebhook.GeneratePost()
 	if post == nil {
-		config.Mattermost.LogError("failed to generate post from webhook")
-		return errors.New("failed to generate post from webhook")
+		config.Mattermost.LogWarn("Received CircleCI Webhook request with an unsupported status", "Status", circleCIWebhook.Status)
+		return nil
 	}
 
+	var failurePosts []*model.Post
 	for _, channelID := range channelIDs {
-		post.ChannelId = channelID
-		if _, appErr := config.Mattermost.CreatePost(post); appErr != nil {
-			config.Mattermost.LogError("Failed to CircleCI status create the post in the channel.", "Error", appErr.Error(), "ChannelID", channelID)
+		channelPost := post.Clone()
+		channelPost.ChannelId = channelID
+		// The post is kept for the excerpt even if its failure thread couldn't be saved
+		createdPost, _ := createNotificationPost(channelPost, circleCIWebhook)
+		if createdPost != nil && circleCIWebhook.Status == serializer.StatusFailure {
+			failurePosts = append(failurePosts, createdPost)
 		}
 	}
 
-	return nil
+	return failurePosts
+}
+
+// sendPersonalNotification sends the notification as a direct message to the user who triggered the job
+// if it matches their notify-me setting. Returns true if the direct message was sent.
+func sendPersonalNotification(userID string, circleCIWebhook *serializer.CircleCIWebhookRequest) bool {
+	setting, err := store.GetNotifyMeSetting(userID)
+	if err != nil || !serializer.ShouldNotifyMe(setting, circleCIWebhook) {
+		return false
+	}
+
+	// The result of a workflow may be received more than once, e.g. from both the orb and the CircleCI webhooks
+	if circleCIWebhook.WorkflowID != "" {
+		if first, err := store.MarkWorkflowNotified(userID, circleCIWebhook.WorkflowID); err != nil || !first {
+			return false
+		}
+	}
+
+	post := circleCIWebhook.GeneratePost()
+	if post == nil {
+		return false
+	}
+
+	return sendDirectMessage(userID, post) == nil
 }
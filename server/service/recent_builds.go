@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+
+	circleci2 "github.com/TomTucka/go-circleci/circleci"
+	"github.com/pkg/errors"
+
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/serializer"
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/util"
+)
+
+// maxRecentBuildsPipelines bounds the number of pipelines scanned for a single recent-builds query,
+// as the workflows of each pipeline need a separate request.
+const maxRecentBuildsPipelines = 100
+
+// ListRecentBuilds returns the most recent workflow runs of the project matching the query, newest first.
+// The pipelines of the project are paginated through until enough builds are found.
+// The workflows of the pipelines of a page are fetched concurrently.
+func ListRecentBuilds(ctx context.Context, client *CircleCIClient, query serializer.RecentBuildsQuery) ([]*serializer.RecentBuild, error) {
+	var builds []*serializer.RecentBuild
+	pageToken := ""
+	scanned := 0
+
+	for {
+		page, err := client.ListProjectPipelines(ctx, query.ProjectSlug, query.Branch, pageToken)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to list the pipelines of the project")
+		}
+
+		var pipelines []circleci2.Pipeline1
+		reachedSince := false
+		for _, pipeline := range page.Items {
+			// pipelines are listed newest first, so none of the remaining ones can match
+			if !query.Since.IsZero() && pipeline.CreatedAt.Before(query.Since) {
+				reachedSince = true
+				break
+			}
+
+			if scanned >= maxRecentBuildsPipelines {
+				break
+			}
+			scanned++
+
+			if !query.Until.IsZero() && pipeline.CreatedAt.After(query.Until) {
+				continue
+			}
+
+			pipelines = append(pipelines, pipeline)
+		}
+
+		buildsByPipeline := make([][]*serializer.RecentBuild, len(pipelines))
+		err = util.RunConcurrently(ctx, len(pipelines), MaxConcurrentRequests, func(ctx context.Context, i int) error {
+			pipelineBuilds, err := listPipelineBuilds(ctx, client, pipelines[i], query)
+			buildsByPipeline[i] = pipelineBuilds
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, pipelineBuilds := range buildsByPipeline {
+			for _, build := range pipelineBuilds {
+				builds = append(builds, build)
+				if len(builds) >= query.Limit {
+					return builds, nil
+				}
+			}
+		}
+
+		if reachedSince || scanned >= maxRecentBuildsPipelines || page.NextPageToken == "" || len(page.Items) == 0 {
+			return builds, nil
+		}
+		pageToken = page.NextPageToken
+	}
+}
+
+func listPipelineBuilds(ctx context.Context, client *CircleCIClient, pipeline circleci2.Pipeline1, query serializer.RecentBuildsQuery) ([]*serializer.RecentBuild, error) {
+	workflows, err := client.ListPipelineWorkflows(ctx, pipeline.Id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list the workflows of pipeline %d", pipeline.Number)
+	}
+
+	var builds []*serializer.RecentBuild
+	for _, workflow := range workflows {
+		if query.Workflow != "" && workflow.Name != query.Workflow {
+			continue
+		}
+
+		if !query.MatchStatus(workflow.Status) {
+			continue
+		}
+
+		build := &serializer.RecentBuild{
+			PipelineNumber: pipeline.Number,
+			WorkflowID:     workflow.Id,
+			WorkflowName:   workflow.Name,
+			Status:         workflow.Status,
+			CreatedAt:      workflow.CreatedAt,
+			StoppedAt:      workflow.StoppedAt,
+		}
+		if pipeline.Vcs != nil {
+			build.Branch = pipeline.Vcs.Branch
+			build.Tag = pipeline.Vcs.Tag
+		}
+		if pipeline.Trigger != nil && pipeline.Trigger.Actor != nil {
+			build.TriggeredBy = pipeline.Trigger.Actor.Login
+		}
+
+		builds = append(builds, build)
+	}
+
+	return builds, nil
+}
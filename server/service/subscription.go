@@ -1,29 +1,15 @@
 package service
 
 import (
-	"encoding/json"
-
 	"github.com/chetanyakan/mattermost-plugin-circleci/server/config"
 	"github.com/chetanyakan/mattermost-plugin-circleci/server/serializer"
 	"github.com/chetanyakan/mattermost-plugin-circleci/server/store"
 )
 
 func AddSubscription(newSubscription serializer.Subscription) error {
-	err := store.AtomicModify(store.SubscriptionsKey, func(initialBytes []byte) ([]byte, error) {
-		subscriptions, err := serializer.SubscriptionsFromJSON(initialBytes)
-		if err != nil {
-			return nil, err
-		}
-
-		subscriptions.Add(newSubscription)
-		modifiedBytes, marshalErr := json.Marshal(subscriptions)
-		if marshalErr != nil {
-			return nil, marshalErr
-		}
-		return modifiedBytes, nil
-	})
+	defer invalidateSubscriptionCache()
 
-	if err != nil {
+	if err := store.SaveSubscription(newSubscription); err != nil {
 		config.Mattermost.LogError("Failed to Add subscription.", "Error", err.Error())
 		return err
 	}
@@ -32,21 +18,9 @@ func AddSubscription(newSubscription serializer.Subscription) error {
 }
 
 func RemoveSubscription(subscription serializer.Subscription) error {
-	err := store.AtomicModify(store.SubscriptionsKey, func(initialBytes []byte) ([]byte, error) {
-		subscriptions, err := serializer.SubscriptionsFromJSON(initialBytes)
-		if err != nil {
-			return nil, err
-		}
-
-		subscriptions.Remove(subscription)
-		modifiedBytes, marshalErr := json.Marshal(subscriptions)
-		if marshalErr != nil {
-			return nil, marshalErr
-		}
-		return modifiedBytes, nil
-	})
+	defer invalidateSubscriptionCache()
 
-	if err != nil {
+	if err := store.DeleteSubscription(subscription); err != nil {
 		config.Mattermost.LogError("Failed to Remove subscription.", "Error", err.Error())
 		return err
 	}
@@ -54,18 +28,85 @@ func RemoveSubscription(subscription serializer.Subscription) error {
 	return nil
 }
 
+// invalidateSubscriptionCache drops the cached subscriptions on this node and on the other nodes of the cluster
+func invalidateSubscriptionCache() {
+	repoSubscriptionCache.invalidate()
+	if _, err := store.BumpSubscriptionsRevision(); err != nil {
+		config.Mattermost.LogWarn("Failed to invalidate the subscription cache of the cluster.", "Error", err.Error())
+	}
+}
+
 func ListSubscriptions(channelID string) ([]serializer.Subscription, error) {
-	b, err := config.Mattermost.KVGet(store.SubscriptionsKey)
+	subscriptions, err := store.GetChannelSubscriptions(channelID)
+	if err != nil {
+		config.Mattermost.LogError("failed to get the list of subscriptions", "Error", err.Error())
+		return nil, err
+	}
+
+	return subscriptions, nil
+}
+
+// GetRepoSubscriptions returns the subscriptions of all the channels subscribed to the repository of the subscription
+// The subscriptions are cached in memory until they are changed on any node of the cluster.
+func GetRepoSubscriptions(s serializer.Subscription) ([]serializer.Subscription, error) {
+	revision, err := store.GetSubscriptionsRevision()
+	if err != nil {
+		return nil, err
+	}
+
+	repoKey := s.GetKey()
+	if subscriptions, ok := repoSubscriptionCache.get(revision, repoKey); ok {
+		return subscriptions, nil
+	}
+
+	repoSubscriptions, err := store.GetRepoSubscriptions(s)
 	if err != nil {
 		config.Mattermost.LogError("failed to get the list of subscriptions", "Error", err.Error())
 		return nil, err
 	}
 
-	subscriptions, appErr := serializer.SubscriptionsFromJSON(b)
-	if appErr != nil {
-		config.Mattermost.LogError("failed to deserialize the list of subscriptions", "Error", appErr.Error())
+	subscriptions := make([]serializer.Subscription, 0, len(repoSubscriptions))
+	for _, subscription := range repoSubscriptions {
+		subscriptions = append(subscriptions, subscription)
+	}
+
+	repoSubscriptionCache.set(revision, repoKey, subscriptions)
+	return subscriptions, nil
+}
+
+// ListAllSubscriptions returns the subscriptions of all the channels
+func ListAllSubscriptions() (*serializer.Subscriptions, error) {
+	channelIDs, err := store.ListSubscribedChannelIDs()
+	if err != nil {
+		config.Mattermost.LogError("failed to get the list of subscribed channels", "Error", err.Error())
 		return nil, err
 	}
 
-	return subscriptions.List(channelID), nil
+	subscriptions := serializer.NewSubscriptions()
+	for _, channelID := range channelIDs {
+		channelSubscriptions, err := ListSubscriptions(channelID)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, s := range channelSubscriptions {
+			subscriptions.Add(s)
+		}
+	}
+
+	return subscriptions, nil
+}
+
+// RemoveChannelSubscriptions removes all the subscriptions of the channels
+func RemoveChannelSubscriptions(channelIDs []string) error {
+	defer invalidateSubscriptionCache()
+
+	for _, channelID := range channelIDs {
+		if err := store.DeleteChannelSubscriptions(channelID); err != nil {
+			config.Mattermost.LogError("Failed to Remove channel subscriptions.", "ChannelID", channelID, "Error", err.Error())
+			return err
+		}
+	}
+
+	return nil
 }
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"sync"
+
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/serializer"
+)
+
+// subscriptionCache keeps the subscriptions of the repositories which received webhooks in memory,
+// so that a webhook doesn't need to read them from the KV store.
+//
+// Plugin cluster events are not available in the supported server versions, so the nodes of a cluster
+// detect changes made by other nodes by comparing the cached revision with the one in the KV store.
+// Reading the revision is a single small read, unlike the subscriptions themselves.
+type subscriptionCache struct {
+	sync.RWMutex
+	revision string
+	byRepo   map[string][]serializer.Subscription
+}
+
+var repoSubscriptionCache = &subscriptionCache{
+	byRepo: map[string][]serializer.Subscription{},
+}
+
+// get returns the cached subscriptions of the repository if the cache is at the revision
+func (c *subscriptionCache) get(revision, repoKey string) ([]serializer.Subscription, bool) {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.revision != revision {
+		return nil, false
+	}
+
+	subscriptions, ok := c.byRepo[repoKey]
+	return subscriptions, ok
+}
+
+// set caches the subscriptions of the repository read at the revision.
+// The whole cache is dropped if the revision has changed.
+func (c *subscriptionCache) set(revision, repoKey string, subscriptions []serializer.Subscription) {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.revision != revision {
+		c.revision = revision
+		c.byRepo = map[string][]serializer.Subscription{}
+	}
+
+	c.byRepo[repoKey] = subscriptions
+}
+
+// invalidate drops the whole cache
+func (c *subscriptionCache) invalidate() {
+	c.Lock()
+	defer c.Unlock()
+
+	c.revision = ""
+	c.byRepo = map[string][]serializer.Subscription{}
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/config"
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/serializer"
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/store"
+)
+
+// createNotificationPost creates the notification post in its channel and returns the created post.
+// Repeated failures of a job on a branch are posted as replies to the first failure,
+// and the thread is closed with a "fixed" reply once the job succeeds again.
+func createNotificationPost(post *model.Post, circleCIWebhook *serializer.CircleCIWebhookRequest) (*model.Post, error) {
+	if circleCIWebhook.Branch == "" {
+		return createPostAndGet(post)
+	}
+
+	rootID, err := store.GetFailureThread(post.ChannelId, circleCIWebhook)
+	if err != nil {
+		// The notification is still posted, outside of the thread
+		return createPostAndGet(post)
+	}
+
+	switch circleCIWebhook.Status {
+	case serializer.StatusFailure:
+		if rootID != "" {
+			post.RootId = rootID
+			if createdPost, err := createPostAndGet(post); err == nil {
+				return createdPost, nil
+			}
+
+			// The root post may have been deleted. Start a new thread instead.
+			post.RootId = ""
+		}
+
+		createdPost, err := createPostAndGet(post)
+		if err != nil {
+			return nil, err
+		}
+
+		return createdPost, store.SaveFailureThread(post.ChannelId, circleCIWebhook, createdPost.Id)
+
+	case serializer.StatusSuccess:
+		if rootID == "" {
+			return createPostAndGet(post)
+		}
+
+		createdPost, err := createFixedReply(post.ChannelId, rootID, circleCIWebhook)
+		if err != nil {
+			// The root post may have been deleted. Post the success as usual.
+			if createdPost, err = createPostAndGet(post); err != nil {
+				return nil, err
+			}
+		}
+
+		return createdPost, store.DeleteFailureThread(post.ChannelId, circleCIWebhook)
+
+	default:
+		return createPostAndGet(post)
+	}
+}
+
+// closeFailureThread replies to the ongoing failure thread of the job in the channel that the job is fixed, and closes the thread.
+// It's used for the channels whose subscription filters out the success notifications.
+func closeFailureThread(channelID string, circleCIWebhook *serializer.CircleCIWebhookRequest) {
+	if circleCIWebhook.Branch == "" {
+		return
+	}
+
+	rootID, err := store.GetFailureThread(channelID, circleCIWebhook)
+	if err != nil || rootID == "" {
+		return
+	}
+
+	_, _ = createFixedReply(channelID, rootID, circleCIWebhook)
+	_ = store.DeleteFailureThread(channelID, circleCIWebhook)
+}
+
+func createFixedReply(channelID, rootID string, circleCIWebhook *serializer.CircleCIWebhookRequest) (*model.Post, error) {
+	fixedPost := circleCIWebhook.GenerateFixedPost()
+	fixedPost.ChannelId = channelID
+	fixedPost.RootId = rootID
+	return createPostAndGet(fixedPost)
+}
+
+func createPost(post *model.Post) error {
+	_, err := createPostAndGet(post)
+	return err
+}
+
+func createPostAndGet(post *model.Post) (*model.Post, error) {
+	createdPost, appErr := config.Mattermost.CreatePost(post)
+	if appErr != nil {
+		config.Mattermost.LogError("Failed to CircleCI status create the post in the channel.", "Error", appErr.Error(), "ChannelID", post.ChannelId)
+		return nil, errors.New(appErr.Error())
+	}
+
+	return createdPost, nil
+}
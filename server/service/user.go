@@ -0,0 +1,255 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	circleci2 "github.com/TomTucka/go-circleci/circleci"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/config"
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/serializer"
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/store"
+)
+
+const DialogFieldAuthToken = "auth_token"
+
+var ErrInvalidAuthToken = errors.New("the auth token is not valid")
+
+// AuthTokenErrorMessage returns the message shown to the user when their auth token can't be read
+func AuthTokenErrorMessage(err error) string {
+	if err == store.ErrUndecryptableToken {
+		return "Your CircleCI auth token could not be read because the encryption key of the plugin has been changed. Please use `/circleci connect` to connect your CircleCI account again."
+	}
+
+	return "Failed to get the auth token. Please try again later. If the problem persists, contact your system administrator."
+}
+
+// ReencryptAuthTokens encrypts all the stored auth tokens with the current encryption key.
+// The users whose token could not be migrated are asked to connect their account again.
+func ReencryptAuthTokens() (int, []string, error) {
+	migrated, failedUserIDs, err := store.ReencryptAuthTokens()
+	if err != nil {
+		config.Mattermost.LogError("Failed to re-encrypt the auth tokens.", "Error", err.Error())
+		return migrated, failedUserIDs, err
+	}
+
+	for _, userID := range failedUserIDs {
+		_ = sendDirectMessage(userID, &model.Post{
+			UserId:  config.BotUserID,
+			Message: AuthTokenErrorMessage(store.ErrUndecryptableToken),
+		})
+	}
+
+	return migrated, failedUserIDs, nil
+}
+
+// GetCircleCIUser fetches the CircleCI user the auth token belongs to.
+// ErrInvalidAuthToken is returned if CircleCI rejects the auth token.
+func GetCircleCIUser(authToken string) (*circleci2.User, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), RequestDeadline)
+	defer cancel()
+
+	user, err := NewCircleCIClient(authToken).GetCurrentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// ConnectCircleCIAccount validates the auth token and saves it for the user.
+// The VCS login of the CircleCI user is mapped to the Mattermost user.
+func ConnectCircleCIAccount(userID, authToken string) (*circleci2.User, error) {
+	circleCIUser, err := GetCircleCIUser(authToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := store.SaveAuthToken(userID, authToken); err != nil {
+		return nil, err
+	}
+
+	if err := mapCircleCIUser(userID, authToken, circleCIUser); err != nil {
+		config.Mattermost.LogWarn("Failed to map the CircleCI user.", "UserID", userID, "Error", err.Error())
+	}
+
+	return circleCIUser, nil
+}
+
+// OpenConnectDialog opens the dialog in which the user enters their auth token
+func OpenConnectDialog(triggerID string) error {
+	dialog := model.OpenDialogRequest{
+		TriggerId: triggerID,
+		URL:       config.URLAPIBase + config.PathConnectDialog,
+		Dialog: model.Dialog{
+			CallbackId:       "connect",
+			Title:            "Connect your CircleCI account",
+			IntroductionText: fmt.Sprintf("Create a personal API token in the [user settings](%s) of CircleCI and enter it below. Connecting replaces the currently connected account.", serializer.GetUserTokensURL()),
+			IconURL:          config.BotIconURL,
+			SubmitLabel:      "Connect",
+			Elements: []model.DialogElement{
+				{
+					DisplayName: "Personal API Token",
+					Name:        DialogFieldAuthToken,
+					Type:        "text",
+					SubType:     "password",
+					Placeholder: "CircleCI personal API token",
+				},
+			},
+		},
+	}
+
+	if appErr := config.Mattermost.OpenInteractiveDialog(dialog); appErr != nil {
+		config.Mattermost.LogError("Failed to open the connect dialog.", "Error", appErr.Error())
+		return errors.New(appErr.Error())
+	}
+
+	return nil
+}
+
+// LinkCircleCIUser maps the VCS login of the CircleCI user the auth token belongs to to the Mattermost user
+func LinkCircleCIUser(userID, authToken string) (*circleci2.User, error) {
+	circleCIUser, err := GetCircleCIUser(authToken)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to fetch the CircleCI user")
+	}
+
+	if err := mapCircleCIUser(userID, authToken, circleCIUser); err != nil {
+		return nil, err
+	}
+
+	return circleCIUser, nil
+}
+
+func mapCircleCIUser(userID, authToken string, circleCIUser *circleci2.User) error {
+	vcsType := getCircleCIUserVCSType(authToken, circleCIUser.Login)
+	if vcsType == "" {
+		return nil
+	}
+
+	return store.SaveUserMapping(&serializer.UserMapping{VCSType: vcsType, VCSLogin: circleCIUser.Login, UserID: userID})
+}
+
+// getCircleCIUserVCSType returns the type of the VCS the login of the CircleCI user belongs to.
+// It's the VCS of the personal organization of the user, or of all their organizations if they have no personal one.
+// An empty string is returned if it can't be found.
+func getCircleCIUserVCSType(authToken, login string) string {
+	if login == "" {
+		return ""
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), RequestDeadline)
+	defer cancel()
+
+	collaborations, err := NewCircleCIClient(authToken).ListCollaborations(ctx)
+	if err != nil {
+		config.Mattermost.LogWarn("Failed to fetch the CircleCI collaborations.", "Login", login, "Error", err.Error())
+		return ""
+	}
+
+	vcsTypes := map[string]bool{}
+	for _, collaboration := range collaborations {
+		vcsType := serializer.NormalizeVCSType(collaboration.VcsType)
+		if strings.EqualFold(collaboration.Name, login) {
+			return vcsType
+		}
+		vcsTypes[vcsType] = true
+	}
+
+	if len(vcsTypes) == 1 {
+		for vcsType := range vcsTypes {
+			return vcsType
+		}
+	}
+
+	return ""
+}
+
+// UnlinkCircleCIUser removes the mapping of the CircleCI user the auth token belongs to,
+// if it is still mapped to the Mattermost user.
+func UnlinkCircleCIUser(userID, authToken string) error {
+	circleCIUser, err := GetCircleCIUser(authToken)
+	if err != nil {
+		return errors.Wrap(err, "failed to fetch the CircleCI user")
+	}
+
+	vcsType := getCircleCIUserVCSType(authToken, circleCIUser.Login)
+	if vcsType == "" {
+		return nil
+	}
+
+	mapping, err := store.GetUserMapping(vcsType, circleCIUser.Login)
+	if err != nil {
+		return err
+	}
+
+	if mapping == nil || mapping.UserID != userID {
+		return nil
+	}
+
+	return store.DeleteUserMapping(vcsType, circleCIUser.Login)
+}
+
+// GetMappedUser returns the Mattermost user mapped to the login on the type of VCS.
+// nil is returned if the login is not mapped or the user doesn't exist anymore.
+func GetMappedUser(vcsType, vcsLogin string) *model.User {
+	if vcsLogin == "" {
+		return nil
+	}
+
+	mapping, err := store.GetUserMapping(vcsType, vcsLogin)
+	if err != nil || mapping == nil {
+		return nil
+	}
+
+	user, appErr := config.Mattermost.GetUser(mapping.UserID)
+	if appErr != nil {
+		config.Mattermost.LogWarn("Failed to get the mapped Mattermost user.", "VCSLogin", vcsLogin, "UserID", mapping.UserID, "Error", appErr.Error())
+		return nil
+	}
+
+	if user.DeleteAt != 0 {
+		return nil
+	}
+
+	return user
+}
+
+// ResolvePipelineActor sets the login of the user who triggered the pipeline of the webhook, as the CircleCI webhooks don't include it.
+// The pipeline is fetched with the service token, so the login is left empty if no service token is configured.
+func ResolvePipelineActor(r *serializer.CircleCIWebhookRequest, pipelineID string) {
+	serviceToken := config.GetConfig().ServiceToken
+	if r.Username != "" || pipelineID == "" || serviceToken == "" {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), RequestDeadline)
+	defer cancel()
+
+	// The pipeline is cached, so that it's only fetched once for all the jobs of the pipeline
+	pipeline, err := NewCircleCIClient(serviceToken).GetPipeline(ctx, pipelineID)
+	if err != nil {
+		config.Mattermost.LogWarn("Failed to fetch the pipeline for finding the user who triggered it.", "PipelineID", pipelineID, "Error", err.Error())
+		return
+	}
+
+	if pipeline.Trigger != nil && pipeline.Trigger.Actor != nil {
+		r.Username = pipeline.Trigger.Actor.Login
+	}
+}
+
+// sendDirectMessage posts a copy of the post in the direct channel between the bot and the user
+func sendDirectMessage(userID string, post *model.Post) error {
+	channel, appErr := config.Mattermost.GetDirectChannel(userID, config.BotUserID)
+	if appErr != nil {
+		config.Mattermost.LogError("Failed to get the direct channel with the user.", "UserID", userID, "Error", appErr.Error())
+		return errors.New(appErr.Error())
+	}
+
+	dm := post.Clone()
+	dm.ChannelId = channel.Id
+	return createPost(dm)
+}
@@ -3,10 +3,13 @@ package service
 import (
 	"errors"
 
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/config"
 	"github.com/chetanyakan/mattermost-plugin-circleci/server/serializer"
 	"github.com/chetanyakan/mattermost-plugin-circleci/server/store"
 )
 
+var ErrVCSNotFound = errors.New("no VCS exists with the provided alias. Use `/circleci list vcs` to see the available VCS")
+
 func GetVCS(alias string) (*serializer.VCS, error) {
 	// first we check for default VCS, then in custom VCS
 	if vcs, found := serializer.DefaultVCSList[alias]; found {
@@ -18,14 +21,34 @@ func GetVCS(alias string) (*serializer.VCS, error) {
 		return nil, err
 	}
 
+	if vcs == nil {
+		return nil, ErrVCSNotFound
+	}
+
 	return vcs, nil
 }
 
+// GetWebhookVCS returns the VCS whose base URL has the same host as the repository of the webhook.
+// Custom VCS are matched before the default ones.
+func GetWebhookVCS(r *serializer.CircleCIWebhookRequest) *serializer.VCS {
+	vcsList, err := GetVCSList()
+	if err != nil {
+		config.Mattermost.LogWarn("Failed to get the VCS list for matching the webhook.", "Error", err.Error())
+		return nil
+	}
+
+	return serializer.FindVCSByRepoURL(vcsList, r.RepoURL)
+}
+
 func AddVCS(vcs *serializer.VCS) error {
 	if _, exists := serializer.DefaultVCSList[vcs.Alias]; exists {
 		return errors.New("VCS alias already exists")
 	}
 
+	if err := vcs.Validate(); err != nil {
+		return err
+	}
+
 	return store.SaveVCS(vcs)
 }
 
@@ -43,9 +66,7 @@ func GetVCSList() ([]*serializer.VCS, error) {
 		return nil, err
 	}
 
-	for _, systemVCS := range serializer.DefaultVCSList {
-		vcsList = append(vcsList, systemVCS)
-	}
+	vcsList = append(vcsList, serializer.DefaultVCSList[serializer.VCSTypeGithub], serializer.DefaultVCSList[serializer.VCSTypeBitbucket])
 
 	return vcsList, nil
 }
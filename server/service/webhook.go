@@ -0,0 +1,39 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/config"
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/store"
+)
+
+const webhookSecretLength = 32
+
+// GetOrCreateWebhookSecret returns the webhook signing secret of a project.
+// A new secret is generated if the project doesn't have one yet or if regenerate is true.
+func GetOrCreateWebhookSecret(projectSlug string, regenerate bool) (string, error) {
+	if !regenerate {
+		secret, err := store.GetWebhookSecret(projectSlug)
+		if err != nil {
+			return "", err
+		}
+
+		if secret != "" {
+			return secret, nil
+		}
+	}
+
+	b := make([]byte, webhookSecretLength)
+	if _, err := rand.Read(b); err != nil {
+		config.Mattermost.LogError("Failed to generate webhook secret.", "Error", err.Error())
+		return "", err
+	}
+
+	secret := hex.EncodeToString(b)
+	if err := store.SaveWebhookSecret(projectSlug, secret); err != nil {
+		return "", err
+	}
+
+	return secret, nil
+}
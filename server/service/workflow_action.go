@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	circleci2 "github.com/TomTucka/go-circleci/circleci"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/config"
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/serializer"
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/store"
+)
+
+const approvalJobType = "approval"
+
+// ExecuteWorkflowAction performs the action of an interactive button of a notification post
+// using the CircleCI account of the user who clicked it.
+func ExecuteWorkflowAction(request *model.PostActionIntegrationRequest) *model.PostActionIntegrationResponse {
+	action, _ := request.Context[serializer.WorkflowActionContextAction].(string)
+	workflowID, _ := request.Context[serializer.WorkflowActionContextWorkflowID].(string)
+	jobName, _ := request.Context[serializer.WorkflowActionContextJobName].(string)
+	if action == "" || workflowID == "" {
+		return &model.PostActionIntegrationResponse{EphemeralText: "Invalid action."}
+	}
+
+	authToken, err := store.GetCircleCIToken(request.UserId)
+	if err != nil {
+		return &model.PostActionIntegrationResponse{EphemeralText: AuthTokenErrorMessage(err)}
+	}
+	if authToken == "" {
+		return &model.PostActionIntegrationResponse{EphemeralText: "Your CircleCI account is not connected to Mattermost. Please use `/circleci connect` to connect your CircleCI and Mattermost accounts."}
+	}
+
+	client := NewCircleCIClient(authToken)
+	ctx, cancel := context.WithTimeout(context.Background(), RequestDeadline)
+	defer cancel()
+
+	var result string
+	switch action {
+	case serializer.WorkflowActionRerun, serializer.WorkflowActionRerunFromFailed:
+		fromFailed := action == serializer.WorkflowActionRerunFromFailed
+		err = client.RerunWorkflow(ctx, workflowID, fromFailed)
+		result = "requested a rerun of the workflow"
+		if fromFailed {
+			result = "requested a rerun of the workflow from the failed jobs"
+		}
+
+	case serializer.WorkflowActionCancel:
+		err = client.CancelWorkflow(ctx, workflowID)
+		result = "canceled the workflow"
+
+	case serializer.WorkflowActionApprove:
+		var approvedJob string
+		approvedJob, err = approveHoldJob(ctx, client, workflowID, jobName)
+		result = fmt.Sprintf("approved the **%s** job", approvedJob)
+
+	default:
+		return &model.PostActionIntegrationResponse{EphemeralText: "Invalid action."}
+	}
+
+	if err != nil {
+		config.Mattermost.LogError("Failed to execute workflow action.", "Action", action, "WorkflowID", workflowID, "Error", err.Error())
+		return &model.PostActionIntegrationResponse{
+			EphemeralText: "Failed to perform the action in CircleCI. Make sure you have access to the project and your auth token is still valid. Error: " + err.Error(),
+		}
+	}
+
+	post, appErr := config.Mattermost.GetPost(request.PostId)
+	if appErr != nil {
+		config.Mattermost.LogWarn("Failed to get the post to update after a workflow action.", "PostID", request.PostId, "Error", appErr.Error())
+		return &model.PostActionIntegrationResponse{EphemeralText: "Successfully " + result + "."}
+	}
+
+	attachments := post.Attachments()
+	if len(attachments) > 0 {
+		attachments[0].Fields = append(attachments[0].Fields, &model.SlackAttachmentField{
+			Title: "Action",
+			Value: fmt.Sprintf("@%s %s.", request.UserName, result),
+			Short: false,
+		})
+
+		// The workflow can't be canceled or approved again
+		if action == serializer.WorkflowActionCancel || action == serializer.WorkflowActionApprove {
+			attachments[0].Actions = nil
+		}
+
+		model.ParseSlackAttachment(post, attachments)
+	}
+
+	return &model.PostActionIntegrationResponse{
+		Update:        post,
+		EphemeralText: "Successfully " + result + ".",
+	}
+}
+
+// approveHoldJob approves the on hold approval job of the workflow.
+// If jobName is the name of an approval job it is preferred over other ones.
+func approveHoldJob(ctx context.Context, client *CircleCIClient, workflowID, jobName string) (string, error) {
+	jobs, err := client.ListWorkflowJobs(ctx, workflowID)
+	if err != nil {
+		return "", err
+	}
+
+	var holdJob *circleci2.Job
+	for i, job := range jobs {
+		if job.Type_ != approvalJobType || job.Status == nil || serializer.NormalizeStatus(*job.Status) != serializer.StatusOnHold {
+			continue
+		}
+
+		if holdJob == nil || job.Name == jobName {
+			holdJob = &jobs[i]
+		}
+	}
+
+	if holdJob == nil {
+		return "", errors.New("no approval job is on hold in the workflow")
+	}
+
+	approvalRequestID := holdJob.ApprovalRequestId
+	if approvalRequestID == "" {
+		approvalRequestID = holdJob.Id
+	}
+
+	if err := client.ApproveJob(ctx, workflowID, approvalRequestID); err != nil {
+		return "", err
+	}
+
+	return holdJob.Name, nil
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/config"
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/serializer"
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/store"
+)
+
+// postWorkflowSummary records the webhook event in the workflow summary of the channel
+// and creates or updates the post showing the summary. The ID of the summary post is returned.
+func postWorkflowSummary(channelID string, circleCIWebhook serializer.CircleCIWebhookRequest) (string, error) {
+	summary, err := store.ModifyWorkflowSummary(channelID, circleCIWebhook.WorkflowID, func(summary *serializer.WorkflowSummary) {
+		summary.AddEvent(circleCIWebhook)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if !summary.Completed && circleCIWebhook.GetEventSubject() == serializer.WebhookEventSubjectJob {
+		if summary, err = completeWorkflowSummary(channelID, summary); err != nil {
+			return "", err
+		}
+	}
+
+	if summary.PostID != "" {
+		return summary.PostID, updateWorkflowSummaryPost(channelID, summary.WorkflowID)
+	}
+
+	post := summary.GeneratePost()
+	post.ChannelId = channelID
+	createdPost, appErr := config.Mattermost.CreatePost(post)
+	if appErr != nil {
+		config.Mattermost.LogError("Failed to create workflow summary post.", "ChannelID", channelID, "Error", appErr.Error())
+		return "", errors.New(appErr.Error())
+	}
+
+	summary, err = store.ModifyWorkflowSummary(channelID, circleCIWebhook.WorkflowID, func(summary *serializer.WorkflowSummary) {
+		if summary.PostID == "" {
+			summary.PostID = createdPost.Id
+		}
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// Another event of the same workflow created the summary post concurrently.
+	// Only keep the post which was recorded first.
+	if summary.PostID != createdPost.Id {
+		if appErr := config.Mattermost.DeletePost(createdPost.Id); appErr != nil {
+			config.Mattermost.LogWarn("Failed to delete duplicate workflow summary post.", "PostID", createdPost.Id, "Error", appErr.Error())
+		}
+	}
+
+	return summary.PostID, updateWorkflowSummaryPost(channelID, summary.WorkflowID)
+}
+
+// completeWorkflowSummary marks the summary as completed once all the jobs of the workflow have finished.
+// The orb only sends job events, so the jobs of the workflow are fetched with the service token to know if it has finished.
+// Without a service token, the summary is only completed by a workflow event.
+func completeWorkflowSummary(channelID string, summary *serializer.WorkflowSummary) (*serializer.WorkflowSummary, error) {
+	serviceToken := config.GetConfig().ServiceToken
+	if serviceToken == "" {
+		return summary, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), RequestDeadline)
+	defer cancel()
+
+	jobs, err := NewCircleCIClient(serviceToken).ListWorkflowJobs(ctx, summary.WorkflowID)
+	if err != nil {
+		config.Mattermost.LogWarn("Failed to fetch the jobs of the workflow.", "WorkflowID", summary.WorkflowID, "Error", err.Error())
+		return summary, nil
+	}
+
+	jobStatuses := map[string]string{}
+	for _, job := range jobs {
+		if job.Status != nil {
+			jobStatuses[job.Name] = *job.Status
+		}
+	}
+
+	status := summary.GetFinishedStatus(jobStatuses)
+	if status == "" {
+		return summary, nil
+	}
+
+	return store.ModifyWorkflowSummary(channelID, summary.WorkflowID, func(summary *serializer.WorkflowSummary) {
+		if !summary.Completed {
+			summary.Completed = true
+			summary.Status = status
+		}
+	})
+}
+
+// updateWorkflowSummaryPost updates the summary post with the latest state of the workflow
+func updateWorkflowSummaryPost(channelID, workflowID string) error {
+	summary, err := store.GetWorkflowSummary(channelID, workflowID)
+	if err != nil {
+		return err
+	}
+
+	post := summary.GeneratePost()
+	post.ChannelId = channelID
+	if _, appErr := config.Mattermost.UpdatePost(post); appErr != nil {
+		config.Mattermost.LogError("Failed to update workflow summary post.", "PostID", post.Id, "Error", appErr.Error())
+		return errors.New(appErr.Error())
+	}
+
+	return nil
+}
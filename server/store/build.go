@@ -0,0 +1,140 @@
+package store
+
+import (
+	"encoding/json"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/config"
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/serializer"
+)
+
+const (
+	trackedBuildPrefix = "tracked_build_"
+	// trackedBuildIndexKey stores the IDs of the pipelines of the tracked builds. Each build is stored in its own value,
+	// so that updating the status of a build doesn't conflict with builds being triggered on other nodes.
+	trackedBuildIndexKey = "tracked_build_index"
+)
+
+func trackedBuildKey(pipelineID string) string {
+	return trackedBuildPrefix + pipelineID
+}
+
+// GetTrackedBuilds returns the tracked builds by pipeline ID
+func GetTrackedBuilds() (serializer.TrackedBuilds, error) {
+	data, appErr := config.Mattermost.KVGet(trackedBuildIndexKey)
+	if appErr != nil {
+		config.Mattermost.LogError("Failed to fetch tracked build index from KV store.", "Error", appErr.Error())
+		return nil, errors.New(appErr.Error())
+	}
+
+	index, err := serializer.TrackedBuildIndexFromJSON(data)
+	if err != nil {
+		return nil, err
+	}
+
+	builds := serializer.TrackedBuilds{}
+	for _, pipelineID := range index {
+		data, appErr := config.Mattermost.KVGet(trackedBuildKey(pipelineID))
+		if appErr != nil {
+			config.Mattermost.LogError("Failed to fetch tracked build from KV store.", "PipelineID", pipelineID, "Error", appErr.Error())
+			return nil, errors.New(appErr.Error())
+		}
+
+		build, err := serializer.TrackedBuildFromJSON(data)
+		if err != nil {
+			return nil, err
+		}
+
+		if build != nil {
+			builds[pipelineID] = build
+		}
+	}
+
+	return builds, nil
+}
+
+// SaveTrackedBuild adds the build to the tracked builds, or updates it if it's already tracked
+func SaveTrackedBuild(build *serializer.TrackedBuild) error {
+	data, err := json.Marshal(build)
+	if err != nil {
+		return err
+	}
+
+	if appErr := config.Mattermost.KVSet(trackedBuildKey(build.PipelineID), data); appErr != nil {
+		config.Mattermost.LogError("Failed to save tracked build to KV store.", "PipelineID", build.PipelineID, "Error", appErr.Error())
+		return errors.New(appErr.Error())
+	}
+
+	return modifyTrackedBuildIndex(func(index serializer.TrackedBuildIndex) serializer.TrackedBuildIndex {
+		return index.Add(build.PipelineID)
+	})
+}
+
+// DeleteTrackedBuilds stops tracking the builds of the pipelines
+func DeleteTrackedBuilds(pipelineIDs []string) error {
+	if err := modifyTrackedBuildIndex(func(index serializer.TrackedBuildIndex) serializer.TrackedBuildIndex {
+		return index.Remove(pipelineIDs)
+	}); err != nil {
+		return err
+	}
+
+	for _, pipelineID := range pipelineIDs {
+		if appErr := config.Mattermost.KVDelete(trackedBuildKey(pipelineID)); appErr != nil {
+			config.Mattermost.LogError("Failed to delete tracked build from KV store.", "PipelineID", pipelineID, "Error", appErr.Error())
+			return errors.New(appErr.Error())
+		}
+	}
+
+	return nil
+}
+
+func modifyTrackedBuildIndex(modify func(index serializer.TrackedBuildIndex) serializer.TrackedBuildIndex) error {
+	err := AtomicModify(trackedBuildIndexKey, func(initialBytes []byte) ([]byte, error) {
+		index, err := serializer.TrackedBuildIndexFromJSON(initialBytes)
+		if err != nil {
+			return nil, err
+		}
+
+		index = modify(index)
+		if len(index) == 0 {
+			return nil, nil
+		}
+
+		return json.Marshal(index)
+	})
+
+	if err != nil {
+		config.Mattermost.LogError("Failed to modify tracked build index.", "Error", err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// TryLock acquires the lock with the key unless it's held already, by this or another node of the cluster.
+// The value identifies the holder of the lock, so that only the holder can release it.
+// The lock is released after expireInSeconds if it isn't unlocked before, so that a node going down doesn't hold it forever.
+func TryLock(key, value string, expireInSeconds int64) (bool, error) {
+	acquired, appErr := config.Mattermost.KVSetWithOptions(key, []byte(value), model.PluginKVSetOptions{
+		Atomic:          true,
+		OldValue:        nil,
+		ExpireInSeconds: expireInSeconds,
+	})
+	if appErr != nil {
+		return false, errors.New(appErr.Error())
+	}
+
+	return acquired, nil
+}
+
+// Unlock releases the lock if it's still held with the value. A lock which has expired and
+// has been acquired by another node in the meantime is left untouched.
+func Unlock(key, value string) error {
+	if _, appErr := config.Mattermost.KVCompareAndDelete(key, []byte(value)); appErr != nil {
+		return errors.New(appErr.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,135 @@
+package store
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/config"
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/serializer"
+)
+
+func TestGetTrackedBuilds(t *testing.T) {
+	api := &plugintest.API{}
+	config.Mattermost = api
+
+	build := &serializer.TrackedBuild{PipelineID: "pipeline1", ProjectSlug: "github/org/repo"}
+	data, err := json.Marshal(build)
+	require.NoError(t, err)
+
+	api.On("KVGet", trackedBuildIndexKey).Return([]byte(`["pipeline1","pipeline2"]`), nil)
+	api.On("KVGet", trackedBuildKey("pipeline1")).Return(data, nil)
+	api.On("KVGet", trackedBuildKey("pipeline2")).Return(nil, nil)
+
+	builds, err := GetTrackedBuilds()
+	require.NoError(t, err)
+	assert.Equal(t, serializer.TrackedBuilds{"pipeline1": build}, builds)
+}
+
+func TestSaveTrackedBuild(t *testing.T) {
+	build := &serializer.TrackedBuild{PipelineID: "pipeline2", ProjectSlug: "github/org/repo"}
+	data, err := json.Marshal(build)
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		name          string
+		index         []byte
+		expectedIndex []byte
+	}{
+		{
+			name:          "new build",
+			index:         []byte(`["pipeline1"]`),
+			expectedIndex: []byte(`["pipeline1","pipeline2"]`),
+		},
+		{
+			name:  "tracked build",
+			index: []byte(`["pipeline1","pipeline2"]`),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			api := &plugintest.API{}
+			config.Mattermost = api
+
+			api.On("KVSet", trackedBuildKey("pipeline2"), data).Return(nil)
+			api.On("KVGet", trackedBuildIndexKey).Return(tc.index, nil)
+			if tc.expectedIndex != nil {
+				api.On("KVSetWithOptions", trackedBuildIndexKey, tc.expectedIndex, model.PluginKVSetOptions{
+					Atomic:   true,
+					OldValue: tc.index,
+				}).Return(true, nil)
+			}
+
+			require.NoError(t, SaveTrackedBuild(build))
+			api.AssertExpectations(t)
+			// the index isn't written when the build is already tracked
+			if tc.expectedIndex == nil {
+				api.AssertNotCalled(t, "KVSetWithOptions")
+			}
+		})
+	}
+}
+
+func TestDeleteTrackedBuilds(t *testing.T) {
+	api := &plugintest.API{}
+	config.Mattermost = api
+
+	index := []byte(`["pipeline1","pipeline2","pipeline3"]`)
+	api.On("KVGet", trackedBuildIndexKey).Return(index, nil)
+	api.On("KVSetWithOptions", trackedBuildIndexKey, []byte(`["pipeline2"]`), model.PluginKVSetOptions{
+		Atomic:   true,
+		OldValue: index,
+	}).Return(true, nil)
+	api.On("KVDelete", trackedBuildKey("pipeline1")).Return(nil)
+	api.On("KVDelete", trackedBuildKey("pipeline3")).Return(nil)
+
+	require.NoError(t, DeleteTrackedBuilds([]string{"pipeline1", "pipeline3"}))
+	api.AssertExpectations(t)
+}
+
+func TestTryLock(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		acquired bool
+		appErr   *model.AppError
+	}{
+		{name: "free lock", acquired: true},
+		{name: "held lock", acquired: false},
+		{name: "KV store error", appErr: model.NewAppError("KVSetWithOptions", "error", nil, "", 500)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			api := &plugintest.API{}
+			config.Mattermost = api
+
+			// the lock is only set if no value exists, and expires so that a node going down doesn't keep it
+			api.On("KVSetWithOptions", "lock", []byte("node1"), model.PluginKVSetOptions{
+				Atomic:          true,
+				OldValue:        nil,
+				ExpireInSeconds: 120,
+			}).Return(tc.acquired, tc.appErr)
+
+			acquired, err := TryLock("lock", "node1", 120)
+			if tc.appErr != nil {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.acquired, acquired)
+		})
+	}
+}
+
+func TestUnlock(t *testing.T) {
+	api := &plugintest.API{}
+	config.Mattermost = api
+
+	// the lock is only removed if it's still held with the value
+	api.On("KVCompareAndDelete", "lock", []byte("node1")).Return(false, nil)
+
+	require.NoError(t, Unlock("lock", "node1"))
+	api.AssertExpectations(t)
+}
@@ -0,0 +1,59 @@
+package store
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/config"
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/serializer"
+)
+
+const defaultProjectPrefix = "default_project_"
+
+func defaultProjectKey(channelID string) string {
+	return defaultProjectPrefix + channelID
+}
+
+// GetDefaultProject returns the default project of the channel, or nil if it hasn't been set
+func GetDefaultProject(channelID string) (*serializer.DefaultProject, error) {
+	data, appErr := config.Mattermost.KVGet(defaultProjectKey(channelID))
+	if appErr != nil {
+		config.Mattermost.LogError("Unable to fetch default project from KVStore.", "ChannelID", channelID, "Error", appErr.Error())
+		return nil, errors.New(appErr.Error())
+	}
+
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	project := &serializer.DefaultProject{}
+	if err := json.Unmarshal(data, project); err != nil {
+		return nil, err
+	}
+
+	return project, nil
+}
+
+func SaveDefaultProject(channelID string, project *serializer.DefaultProject) error {
+	data, err := json.Marshal(project)
+	if err != nil {
+		return err
+	}
+
+	if appErr := config.Mattermost.KVSet(defaultProjectKey(channelID), data); appErr != nil {
+		config.Mattermost.LogError("Unable to save default project to KVStore.", "ChannelID", channelID, "Error", appErr.Error())
+		return errors.New(appErr.Error())
+	}
+
+	return nil
+}
+
+func DeleteDefaultProject(channelID string) error {
+	if appErr := config.Mattermost.KVDelete(defaultProjectKey(channelID)); appErr != nil {
+		config.Mattermost.LogError("Unable to delete default project from KVStore.", "ChannelID", channelID, "Error", appErr.Error())
+		return errors.New(appErr.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,33 @@
+package store
+
+import (
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/config"
+)
+
+const subscriptionsRevisionKey = "subs_revision"
+
+// GetSubscriptionsRevision returns the identifier of the last change of the subscriptions.
+// It is used by every node of a cluster for detecting that its cached subscriptions are stale.
+func GetSubscriptionsRevision() (string, error) {
+	revision, appErr := config.Mattermost.KVGet(subscriptionsRevisionKey)
+	if appErr != nil {
+		config.Mattermost.LogError("Failed to fetch subscriptions revision from KV store.", "Error", appErr.Error())
+		return "", errors.New(appErr.Error())
+	}
+
+	return string(revision), nil
+}
+
+// BumpSubscriptionsRevision records that the subscriptions have changed and returns the new revision
+func BumpSubscriptionsRevision() (string, error) {
+	revision := model.NewId()
+	if appErr := config.Mattermost.KVSet(subscriptionsRevisionKey, []byte(revision)); appErr != nil {
+		config.Mattermost.LogError("Failed to save subscriptions revision to KV store.", "Error", appErr.Error())
+		return "", errors.New(appErr.Error())
+	}
+
+	return revision, nil
+}
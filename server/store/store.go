@@ -3,7 +3,9 @@ package store
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 
+	"github.com/mattermost/mattermost-server/v5/model"
 	"github.com/pkg/errors"
 
 	"github.com/chetanyakan/mattermost-plugin-circleci/server/config"
@@ -12,37 +14,105 @@ import (
 )
 
 const (
-	SubscriptionsKey = "circleci_subscriptions"
-
 	vcsKeyPrefix        = "vcs_"
 	listVCSKey          = "vcs_list"
 	circleciTokenPrefix = "circleci_token_"
+	notifyMePrefix      = "circleci_notify_me_"
+	notifiedPrefix      = "circleci_notified_"
+
+	// notifiedExpiry is the duration in seconds for which a sent personal notification is remembered
+	notifiedExpiry = 24 * 60 * 60
 )
 
 func CircleCIAuthTokenKey(userID string) string {
 	return circleciTokenPrefix + userID
 }
 
+func notifyMeKey(userID string) string {
+	return notifyMePrefix + userID
+}
+
+// GetNotifyMeSetting returns the personal notification setting of the user.
+// Personal notifications are off if the user hasn't set them up.
+func GetNotifyMeSetting(userID string) (string, error) {
+	setting, appErr := config.Mattermost.KVGet(notifyMeKey(userID))
+	if appErr != nil {
+		config.Mattermost.LogError("Unable to fetch notify-me setting from KVStore.", "Error", appErr.Error())
+		return "", errors.New(appErr.Error())
+	}
+
+	if len(setting) == 0 {
+		return serializer.NotifyMeOff, nil
+	}
+
+	return string(setting), nil
+}
+
+func SaveNotifyMeSetting(userID, setting string) error {
+	if appErr := config.Mattermost.KVSet(notifyMeKey(userID), []byte(setting)); appErr != nil {
+		config.Mattermost.LogError("Unable to save notify-me setting to KVStore.", "Error", appErr.Error())
+		return errors.New(appErr.Error())
+	}
+
+	return nil
+}
+
+// MarkWorkflowNotified records that the user has been notified of the result of the workflow.
+// false is returned if the user had already been notified of it.
+func MarkWorkflowNotified(userID, workflowID string) (bool, error) {
+	marked, appErr := config.Mattermost.KVSetWithOptions(hashedKey(notifiedPrefix, userID+"_"+workflowID), []byte(workflowID), model.PluginKVSetOptions{
+		Atomic:          true,
+		OldValue:        nil,
+		ExpireInSeconds: notifiedExpiry,
+	})
+	if appErr != nil {
+		config.Mattermost.LogError("Unable to save the sent personal notification to KVStore.", "Error", appErr.Error())
+		return false, errors.New(appErr.Error())
+	}
+
+	return marked, nil
+}
+
+// ErrUndecryptableToken is returned when the stored auth token was encrypted with a key which is not configured anymore
+var ErrUndecryptableToken = errors.New("the auth token was encrypted with an unknown key")
+
 func GetCircleCIToken(userID string) (string, error) {
-	authToken, appErr := config.Mattermost.KVGet(CircleCIAuthTokenKey(userID))
+	authToken, _, err := getAndMigrateAuthToken(userID)
+	return authToken, err
+}
+
+// getAndMigrateAuthToken decrypts the auth token of the user. Tokens encrypted with the previous key
+// or in the unversioned format are transparently encrypted again with the current key.
+func getAndMigrateAuthToken(userID string) (authToken string, migrated bool, err error) {
+	encryptedToken, appErr := config.Mattermost.KVGet(CircleCIAuthTokenKey(userID))
 	if appErr != nil {
-		return "", appErr
+		return "", false, appErr
 	}
 
-	if len(authToken) == 0 {
-		return "", nil
+	if len(encryptedToken) == 0 {
+		return "", false, nil
 	}
 
-	unencryptedToken, err := util.Decrypt([]byte(config.GetConfig().EncryptionKey), string(authToken))
+	authToken, keyIndex, err := util.DecryptVersioned(config.GetConfig().GetEncryptionKeys(), string(encryptedToken))
 	if err != nil {
-		return "", err
+		config.Mattermost.LogWarn("Unable to decrypt auth token.", "UserID", userID, "Error", err.Error())
+		return "", false, ErrUndecryptableToken
+	}
+
+	if keyIndex == 0 && util.IsCurrentVersion(string(encryptedToken)) {
+		return authToken, false, nil
 	}
 
-	return unencryptedToken, nil
+	if err := SaveAuthToken(userID, authToken); err != nil {
+		config.Mattermost.LogWarn("Unable to migrate auth token to the current encryption key.", "UserID", userID, "Error", err.Error())
+		return authToken, false, nil
+	}
+
+	return authToken, true, nil
 }
 
 func SaveAuthToken(userID, authToken string) error {
-	encryptedToken, err := util.Encrypt([]byte(config.GetConfig().EncryptionKey), authToken)
+	encryptedToken, err := util.EncryptVersioned([]byte(config.GetConfig().EncryptionKey), authToken)
 	if err != nil {
 		config.Mattermost.LogError("Unable to encrypt auth token.", "Error ", err.Error())
 		return err
@@ -56,6 +126,33 @@ func SaveAuthToken(userID, authToken string) error {
 	return nil
 }
 
+// ReencryptAuthTokens encrypts all the stored auth tokens with the current encryption key.
+// The IDs of the users whose token could not be decrypted with any of the configured keys are returned.
+func ReencryptAuthTokens() (migrated int, failedUserIDs []string, err error) {
+	keys, err := listKeys(circleciTokenPrefix)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	for _, key := range keys {
+		userID := strings.TrimPrefix(key, circleciTokenPrefix)
+		_, tokenMigrated, err := getAndMigrateAuthToken(userID)
+		if err == ErrUndecryptableToken {
+			failedUserIDs = append(failedUserIDs, userID)
+			continue
+		}
+		if err != nil {
+			return migrated, failedUserIDs, err
+		}
+
+		if tokenMigrated {
+			migrated++
+		}
+	}
+
+	return migrated, failedUserIDs, nil
+}
+
 func GetVCS(alias string) (*serializer.VCS, error) {
 	key := vcsKeyPrefix + alias
 	data, err := config.Mattermost.KVGet(key)
@@ -105,6 +202,10 @@ func GetVCSList() ([]*serializer.VCS, error) {
 		return nil, errors.New(appErr.Error())
 	}
 
+	if len(data) == 0 {
+		return []*serializer.VCS{}, nil
+	}
+
 	var vcsList []*serializer.VCS
 	if err := json.Unmarshal(data, &vcsList); err != nil {
 		config.Mattermost.LogError(fmt.Sprintf("Failed to unmarshal VCS list. Error: %s", err.Error()))
@@ -122,59 +223,43 @@ func DeleteVCS(alias string) error {
 		return err
 	}
 
-	return nil
+	return modifyVCSList(func(vcsList []serializer.VCS) []serializer.VCS {
+		updatedList := make([]serializer.VCS, 0, len(vcsList))
+		for _, vcs := range vcsList {
+			if vcs.Alias != alias {
+				updatedList = append(updatedList, vcs)
+			}
+		}
+		return updatedList
+	})
 }
 
 func addToVCSList(vcs serializer.VCS) error {
-	vcsListData, appErr := config.Mattermost.KVGet(listVCSKey)
-	if appErr != nil {
-		config.Mattermost.LogError("Failed to fetch list of VCS from KV store. Error: " + appErr.Error())
-		return appErr
-	}
-
-	var dataToUnmarshal []byte
-	if len(vcsListData) == 0 {
-		dataToUnmarshal = []byte("[]")
-	} else {
-		dataToUnmarshal = vcsListData
-	}
-
-	var vcsList *[]serializer.VCS
-	if err := json.Unmarshal(dataToUnmarshal, &vcsList); err != nil {
-		config.Mattermost.LogError("Failed to unmarshal VCS list. Error: " + err.Error())
-		return err
-	}
-
-	config.Mattermost.LogInfo(fmt.Sprintf("%v", vcsList))
-
-	*vcsList = append(*vcsList, vcs)
-
-	updatedVCSListData, vcsListErr := json.Marshal(*vcsList)
-	if vcsListErr != nil {
-		config.Mattermost.LogError("Failed to marshal updated VCS list. Error: " + vcsListErr.Error())
-		return vcsListErr
-	}
-
-	// TODO : use AtomicModify
-	err := util.KVCompareAndSet(listVCSKey, vcsListData, updatedVCSListData, func(oldData []byte) ([]byte, error) {
-		var oldList []serializer.VCS
-		if err := json.Unmarshal(oldData, &oldList); err != nil {
-			config.Mattermost.LogError("Failed to unmarshal VCS list.", "Error", err.Error())
-			return nil, err
+	return modifyVCSList(func(vcsList []serializer.VCS) []serializer.VCS {
+		for i := range vcsList {
+			if vcsList[i].Alias == vcs.Alias {
+				vcsList[i] = vcs
+				return vcsList
+			}
 		}
+		return append(vcsList, vcs)
+	})
+}
 
-		vcsList := append(oldList, vcs)
-		newList, err := json.Marshal(vcsList)
-		if err != nil {
-			config.Mattermost.LogError("Failed to marshal updated VCS list.", "Error", err.Error())
-			return nil, err
+func modifyVCSList(modify func(vcsList []serializer.VCS) []serializer.VCS) error {
+	err := AtomicModify(listVCSKey, func(initialBytes []byte) ([]byte, error) {
+		vcsList := []serializer.VCS{}
+		if len(initialBytes) > 0 {
+			if err := json.Unmarshal(initialBytes, &vcsList); err != nil {
+				return nil, err
+			}
 		}
 
-		return newList, nil
+		return json.Marshal(modify(vcsList))
 	})
 
 	if err != nil {
-		config.Mattermost.LogError(fmt.Sprintf("Failed to save VCS to KV store by compare and set. VCS: [%v], error: [%s]", vcs, err.Error()))
+		config.Mattermost.LogError("Failed to update the VCS list in KV store.", "Error", err.Error())
 		return err
 	}
 
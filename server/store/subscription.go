@@ -0,0 +1,224 @@
+package store
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/config"
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/serializer"
+)
+
+const (
+	// legacySubscriptionsKey stored all the subscriptions in a single value before they were split per repository
+	legacySubscriptionsKey = "circleci_subscriptions"
+
+	repoSubscriptionsPrefix    = "subs_repo_"
+	channelSubscriptionsPrefix = "subs_channel_"
+)
+
+func repoSubscriptionsKey(repoKey string) string {
+	return hashedKey(repoSubscriptionsPrefix, repoKey)
+}
+
+func channelSubscriptionsKey(channelID string) string {
+	return channelSubscriptionsPrefix + channelID
+}
+
+// GetRepoSubscriptions returns the subscriptions of all the channels to the repository of the subscription
+func GetRepoSubscriptions(s serializer.Subscription) (serializer.RepoSubscriptions, error) {
+	data, appErr := config.Mattermost.KVGet(repoSubscriptionsKey(s.GetKey()))
+	if appErr != nil {
+		config.Mattermost.LogError("Failed to fetch repository subscriptions from KV store.", "Error", appErr.Error())
+		return nil, errors.New(appErr.Error())
+	}
+
+	return serializer.RepoSubscriptionsFromJSON(data)
+}
+
+// GetChannelSubscriptions returns the subscriptions of the channel
+func GetChannelSubscriptions(channelID string) ([]serializer.Subscription, error) {
+	data, appErr := config.Mattermost.KVGet(channelSubscriptionsKey(channelID))
+	if appErr != nil {
+		config.Mattermost.LogError("Failed to fetch channel subscriptions from KV store.", "ChannelID", channelID, "Error", appErr.Error())
+		return nil, errors.New(appErr.Error())
+	}
+
+	index, err := serializer.ChannelSubscriptionIndexFromJSON(data)
+	if err != nil {
+		return nil, err
+	}
+
+	subscriptions := make([]serializer.Subscription, 0, len(index))
+	for _, repoKey := range index {
+		data, appErr := config.Mattermost.KVGet(repoSubscriptionsKey(repoKey))
+		if appErr != nil {
+			config.Mattermost.LogError("Failed to fetch repository subscriptions from KV store.", "Error", appErr.Error())
+			return nil, errors.New(appErr.Error())
+		}
+
+		repoSubscriptions, err := serializer.RepoSubscriptionsFromJSON(data)
+		if err != nil {
+			return nil, err
+		}
+
+		if s, found := repoSubscriptions[channelID]; found {
+			subscriptions = append(subscriptions, s)
+		}
+	}
+
+	return subscriptions, nil
+}
+
+// ListSubscribedChannelIDs returns the IDs of all the channels having subscriptions
+func ListSubscribedChannelIDs() ([]string, error) {
+	keys, err := listKeys(channelSubscriptionsPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	channelIDs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		channelIDs = append(channelIDs, strings.TrimPrefix(key, channelSubscriptionsPrefix))
+	}
+
+	return channelIDs, nil
+}
+
+// SaveSubscription adds or updates the subscription of a channel to a repository
+func SaveSubscription(s serializer.Subscription) error {
+	repoKey := s.GetKey()
+	if err := modifyRepoSubscriptions(repoKey, func(subscriptions serializer.RepoSubscriptions) {
+		subscriptions[s.ChannelID] = s
+	}); err != nil {
+		return err
+	}
+
+	return modifyChannelSubscriptionIndex(s.ChannelID, func(index serializer.ChannelSubscriptionIndex) serializer.ChannelSubscriptionIndex {
+		return index.Add(repoKey)
+	})
+}
+
+// DeleteSubscription removes the subscription of a channel to a repository
+func DeleteSubscription(s serializer.Subscription) error {
+	repoKey := s.GetKey()
+	if err := modifyRepoSubscriptions(repoKey, func(subscriptions serializer.RepoSubscriptions) {
+		delete(subscriptions, s.ChannelID)
+	}); err != nil {
+		return err
+	}
+
+	return modifyChannelSubscriptionIndex(s.ChannelID, func(index serializer.ChannelSubscriptionIndex) serializer.ChannelSubscriptionIndex {
+		return index.Remove(repoKey)
+	})
+}
+
+// DeleteChannelSubscriptions removes all the subscriptions of a channel
+func DeleteChannelSubscriptions(channelID string) error {
+	subscriptions, err := GetChannelSubscriptions(channelID)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range subscriptions {
+		if err := modifyRepoSubscriptions(s.GetKey(), func(subscriptions serializer.RepoSubscriptions) {
+			delete(subscriptions, channelID)
+		}); err != nil {
+			return err
+		}
+	}
+
+	if appErr := config.Mattermost.KVDelete(channelSubscriptionsKey(channelID)); appErr != nil {
+		config.Mattermost.LogError("Failed to delete channel subscriptions from KV store.", "ChannelID", channelID, "Error", appErr.Error())
+		return errors.New(appErr.Error())
+	}
+
+	return nil
+}
+
+func modifyRepoSubscriptions(repoKey string, modify func(subscriptions serializer.RepoSubscriptions)) error {
+	key := repoSubscriptionsKey(repoKey)
+	err := AtomicModify(key, func(initialBytes []byte) ([]byte, error) {
+		subscriptions, err := serializer.RepoSubscriptionsFromJSON(initialBytes)
+		if err != nil {
+			return nil, err
+		}
+
+		modify(subscriptions)
+		if len(subscriptions) == 0 {
+			return nil, nil
+		}
+
+		return json.Marshal(subscriptions)
+	})
+
+	if err != nil {
+		config.Mattermost.LogError("Failed to modify repository subscriptions.", "Error", err.Error())
+		return err
+	}
+
+	return nil
+}
+
+func modifyChannelSubscriptionIndex(channelID string, modify func(index serializer.ChannelSubscriptionIndex) serializer.ChannelSubscriptionIndex) error {
+	err := AtomicModify(channelSubscriptionsKey(channelID), func(initialBytes []byte) ([]byte, error) {
+		index, err := serializer.ChannelSubscriptionIndexFromJSON(initialBytes)
+		if err != nil {
+			return nil, err
+		}
+
+		index = modify(index)
+		if len(index) == 0 {
+			return nil, nil
+		}
+
+		return json.Marshal(index)
+	})
+
+	if err != nil {
+		config.Mattermost.LogError("Failed to modify channel subscription index.", "ChannelID", channelID, "Error", err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// MigrateLegacySubscriptions moves the subscriptions stored in the single legacy value
+// to the per repository and per channel values. The legacy value is removed once all the subscriptions are migrated.
+func MigrateLegacySubscriptions() error {
+	data, appErr := config.Mattermost.KVGet(legacySubscriptionsKey)
+	if appErr != nil {
+		return errors.Wrap(appErr, "failed to fetch legacy subscriptions")
+	}
+
+	if len(data) == 0 {
+		return nil
+	}
+
+	legacySubscriptions, err := serializer.SubscriptionsFromJSON(data)
+	if err != nil {
+		return errors.Wrap(err, "failed to deserialize legacy subscriptions")
+	}
+
+	count := 0
+	for _, channelSubscriptions := range legacySubscriptions.ByChannelID {
+		for _, s := range channelSubscriptions {
+			if err := SaveSubscription(s); err != nil {
+				return errors.Wrap(err, "failed to migrate subscription")
+			}
+			count++
+		}
+	}
+
+	if appErr := config.Mattermost.KVDelete(legacySubscriptionsKey); appErr != nil {
+		return errors.Wrap(appErr, "failed to delete legacy subscriptions")
+	}
+
+	if _, err := BumpSubscriptionsRevision(); err != nil {
+		return err
+	}
+
+	config.Mattermost.LogInfo("Migrated subscriptions to the per repository storage.", "Count", count)
+	return nil
+}
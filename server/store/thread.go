@@ -0,0 +1,54 @@
+package store
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/config"
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/serializer"
+)
+
+const (
+	failureThreadPrefix = "failure_thread_"
+
+	// failureThreadExpiry is the duration in seconds after which a failure thread is forgotten,
+	// so that a branch failing again after a long time starts a new thread.
+	failureThreadExpiry = 30 * 24 * 60 * 60
+)
+
+// failureThreadKey identifies the failures of a job on a branch of a repository in a channel
+func failureThreadKey(channelID string, r *serializer.CircleCIWebhookRequest) string {
+	subscription := r.GetSubscription()
+	return hashedKey(failureThreadPrefix, fmt.Sprintf("%s_%s_%s_%s_%s", channelID, subscription.GetKey(), r.Branch, r.GetEventSubject(), r.JobName))
+}
+
+// GetFailureThread returns the ID of the root post of the ongoing failure thread.
+// An empty string is returned if the job is not failing.
+func GetFailureThread(channelID string, r *serializer.CircleCIWebhookRequest) (string, error) {
+	rootID, appErr := config.Mattermost.KVGet(failureThreadKey(channelID, r))
+	if appErr != nil {
+		config.Mattermost.LogError("Failed to fetch failure thread from KV store.", "ChannelID", channelID, "Error", appErr.Error())
+		return "", errors.New(appErr.Error())
+	}
+
+	return string(rootID), nil
+}
+
+func SaveFailureThread(channelID string, r *serializer.CircleCIWebhookRequest, rootID string) error {
+	if appErr := config.Mattermost.KVSetWithExpiry(failureThreadKey(channelID, r), []byte(rootID), failureThreadExpiry); appErr != nil {
+		config.Mattermost.LogError("Failed to save failure thread to KV store.", "ChannelID", channelID, "Error", appErr.Error())
+		return errors.New(appErr.Error())
+	}
+
+	return nil
+}
+
+func DeleteFailureThread(channelID string, r *serializer.CircleCIWebhookRequest) error {
+	if appErr := config.Mattermost.KVDelete(failureThreadKey(channelID, r)); appErr != nil {
+		config.Mattermost.LogError("Failed to delete failure thread from KV store.", "ChannelID", channelID, "Error", appErr.Error())
+		return errors.New(appErr.Error())
+	}
+
+	return nil
+}
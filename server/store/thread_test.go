@@ -0,0 +1,86 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/config"
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/serializer"
+)
+
+func TestFailureThreadKey(t *testing.T) {
+	newRequest := func() *serializer.CircleCIWebhookRequest {
+		return &serializer.CircleCIWebhookRequest{
+			OrgName:  "org",
+			RepoName: "repo",
+			RepoURL:  "https://github.com/org/repo",
+			Branch:   "main",
+			JobName:  "test",
+		}
+	}
+	key := failureThreadKey("channel1", newRequest())
+
+	for _, tc := range []struct {
+		name      string
+		channelID string
+		modify    func(r *serializer.CircleCIWebhookRequest)
+		sameKey   bool
+	}{
+		{
+			name:      "same job",
+			channelID: "channel1",
+			modify: func(r *serializer.CircleCIWebhookRequest) {
+				r.Status = serializer.StatusSuccess
+				r.BuildNum = "42"
+			},
+			sameKey: true,
+		},
+		{
+			name:      "other channel",
+			channelID: "channel2",
+			modify:    func(r *serializer.CircleCIWebhookRequest) {},
+		},
+		{
+			name:      "other repository",
+			channelID: "channel1",
+			modify:    func(r *serializer.CircleCIWebhookRequest) { r.RepoName = "other" },
+		},
+		{
+			name:      "other branch",
+			channelID: "channel1",
+			modify:    func(r *serializer.CircleCIWebhookRequest) { r.Branch = "develop" },
+		},
+		{
+			name:      "other job",
+			channelID: "channel1",
+			modify:    func(r *serializer.CircleCIWebhookRequest) { r.JobName = "lint" },
+		},
+		{
+			name:      "workflow",
+			channelID: "channel1",
+			modify:    func(r *serializer.CircleCIWebhookRequest) { r.EventType = serializer.WebhookEventSubjectWorkflow },
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := newRequest()
+			tc.modify(r)
+
+			assert.Equal(t, tc.sameKey, failureThreadKey(tc.channelID, r) == key)
+		})
+	}
+}
+
+func TestSaveFailureThread(t *testing.T) {
+	api := &plugintest.API{}
+	config.Mattermost = api
+
+	r := &serializer.CircleCIWebhookRequest{OrgName: "org", RepoName: "repo", Branch: "main", JobName: "test"}
+	// the thread expires, so that a job failing again after a long time starts a new thread
+	api.On("KVSetWithExpiry", failureThreadKey("channel1", r), []byte("root1"), int64(30*24*60*60)).Return(nil)
+
+	require.NoError(t, SaveFailureThread("channel1", r, "root1"))
+	api.AssertExpectations(t)
+}
@@ -0,0 +1,81 @@
+package store
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/config"
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/serializer"
+)
+
+const userMappingPrefix = "user_mapping_"
+
+func userMappingKey(vcsType, vcsLogin string) string {
+	return hashedKey(userMappingPrefix, vcsType+"/"+serializer.NormalizeVCSLogin(vcsLogin))
+}
+
+// GetUserMapping returns the Mattermost user mapped to the login on the type of VCS.
+// nil is returned if the login is not mapped.
+func GetUserMapping(vcsType, vcsLogin string) (*serializer.UserMapping, error) {
+	data, appErr := config.Mattermost.KVGet(userMappingKey(vcsType, vcsLogin))
+	if appErr != nil {
+		config.Mattermost.LogError("Failed to fetch user mapping from KV store.", "VCSLogin", vcsLogin, "Error", appErr.Error())
+		return nil, errors.New(appErr.Error())
+	}
+
+	return serializer.UserMappingFromJSON(data)
+}
+
+func SaveUserMapping(mapping *serializer.UserMapping) error {
+	mapping.VCSLogin = serializer.NormalizeVCSLogin(mapping.VCSLogin)
+
+	data, err := json.Marshal(mapping)
+	if err != nil {
+		return err
+	}
+
+	if appErr := config.Mattermost.KVSet(userMappingKey(mapping.VCSType, mapping.VCSLogin), data); appErr != nil {
+		config.Mattermost.LogError("Failed to save user mapping to KV store.", "VCSLogin", mapping.VCSLogin, "Error", appErr.Error())
+		return errors.New(appErr.Error())
+	}
+
+	return nil
+}
+
+func DeleteUserMapping(vcsType, vcsLogin string) error {
+	if appErr := config.Mattermost.KVDelete(userMappingKey(vcsType, vcsLogin)); appErr != nil {
+		config.Mattermost.LogError("Failed to delete user mapping from KV store.", "VCSLogin", vcsLogin, "Error", appErr.Error())
+		return errors.New(appErr.Error())
+	}
+
+	return nil
+}
+
+// ListUserMappings returns all the user mappings saved in the KV store
+func ListUserMappings() ([]*serializer.UserMapping, error) {
+	keys, err := listKeys(userMappingPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var mappings []*serializer.UserMapping
+	for _, key := range keys {
+		data, appErr := config.Mattermost.KVGet(key)
+		if appErr != nil {
+			return nil, errors.New(appErr.Error())
+		}
+
+		mapping, err := serializer.UserMappingFromJSON(data)
+		if err != nil {
+			config.Mattermost.LogWarn("Failed to deserialize user mapping.", "Key", key, "Error", err.Error())
+			continue
+		}
+
+		if mapping != nil {
+			mappings = append(mappings, mapping)
+		}
+	}
+
+	return mappings, nil
+}
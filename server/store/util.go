@@ -2,16 +2,37 @@ package store
 
 import (
 	"bytes"
+	"strings"
 	"time"
 
 	"github.com/mattermost/mattermost-server/v5/model"
 	"github.com/pkg/errors"
 
 	"github.com/chetanyakan/mattermost-plugin-circleci/server/config"
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/util"
 )
 
+const listKeysPerPage = 100
+
+// hashedKey builds a KV store key from a prefix and the hash of an identifier.
+// The key is truncated to stay within the key length limit of the KV store.
+func hashedKey(prefix, identifier string) string {
+	key := prefix + util.GetKeyHash(identifier)
+	if len(key) > model.KEY_VALUE_KEY_MAX_RUNES {
+		key = key[:model.KEY_VALUE_KEY_MAX_RUNES]
+	}
+
+	return key
+}
+
 // from https://github.com/mattermost/mattermost-plugin-jira/blob/0c04ea41daf62fcfb6682644ea5927370fc7ebe5/server/subscribe.go#L655
 func AtomicModify(key string, modify func(initialValue []byte) ([]byte, error)) error {
+	return AtomicModifyWithExpiry(key, 0, modify)
+}
+
+// AtomicModifyWithExpiry works like AtomicModify but the stored value expires after the specified number of seconds.
+// The value never expires if expireInSeconds is 0.
+func AtomicModifyWithExpiry(key string, expireInSeconds int64, modify func(initialValue []byte) ([]byte, error)) error {
 	readModify := func() ([]byte, []byte, error) {
 		initialBytes, appErr := config.Mattermost.KVGet(key)
 		if appErr != nil {
@@ -27,35 +48,56 @@ func AtomicModify(key string, modify func(initialValue []byte) ([]byte, error))
 	}
 
 	var (
-		retryLimit     = 5
-		retryWait      = 30 * time.Millisecond
-		success        = false
-		currentAttempt = 0
+		retryLimit = 5
+		retryWait  = 30 * time.Millisecond
 	)
-	for !success {
+	for attempt := 0; attempt < retryLimit; attempt++ {
 		initialBytes, newValue, err := readModify()
-
 		if err != nil {
 			return err
 		}
 
-		var setError *model.AppError
-		success, setError = config.Mattermost.KVCompareAndSet(key, initialBytes, newValue)
+		if bytes.Equal(initialBytes, newValue) {
+			return nil
+		}
+
+		success, setError := config.Mattermost.KVSetWithOptions(key, newValue, model.PluginKVSetOptions{
+			Atomic:          true,
+			OldValue:        initialBytes,
+			ExpireInSeconds: expireInSeconds,
+		})
 		if setError != nil {
 			return errors.Wrap(setError, "problem writing value")
 		}
 
-		if currentAttempt == 0 && bytes.Equal(initialBytes, newValue) {
+		if success {
 			return nil
 		}
 
-		currentAttempt++
-		if currentAttempt >= retryLimit {
-			return errors.New("reached write attempt limit")
-		}
-
 		time.Sleep(retryWait)
 	}
 
-	return nil
+	return errors.New("reached write attempt limit")
+}
+
+// listKeys returns all the keys of the KV store starting with the prefix
+func listKeys(prefix string) ([]string, error) {
+	var keys []string
+	for page := 0; ; page++ {
+		pageKeys, appErr := config.Mattermost.KVList(page, listKeysPerPage)
+		if appErr != nil {
+			config.Mattermost.LogError("Failed to list keys from KV store.", "Error", appErr.Error())
+			return nil, errors.New(appErr.Error())
+		}
+
+		for _, key := range pageKeys {
+			if strings.HasPrefix(key, prefix) {
+				keys = append(keys, key)
+			}
+		}
+
+		if len(pageKeys) < listKeysPerPage {
+			return keys, nil
+		}
+	}
 }
@@ -0,0 +1,34 @@
+package store
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/config"
+)
+
+const webhookSecretPrefix = "webhook_secret_"
+
+func webhookSecretKey(projectSlug string) string {
+	return hashedKey(webhookSecretPrefix, projectSlug)
+}
+
+// GetWebhookSecret returns the secret used to sign the CircleCI webhooks of a project.
+// An empty string is returned if no secret has been generated for the project.
+func GetWebhookSecret(projectSlug string) (string, error) {
+	secret, appErr := config.Mattermost.KVGet(webhookSecretKey(projectSlug))
+	if appErr != nil {
+		config.Mattermost.LogError("Unable to fetch webhook secret from KVStore.", "ProjectSlug", projectSlug, "Error", appErr.Error())
+		return "", errors.New(appErr.Error())
+	}
+
+	return string(secret), nil
+}
+
+func SaveWebhookSecret(projectSlug, secret string) error {
+	if appErr := config.Mattermost.KVSet(webhookSecretKey(projectSlug), []byte(secret)); appErr != nil {
+		config.Mattermost.LogError("Unable to save webhook secret to KVStore.", "ProjectSlug", projectSlug, "Error", appErr.Error())
+		return errors.New(appErr.Error())
+	}
+
+	return nil
+}
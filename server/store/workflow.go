@@ -0,0 +1,51 @@
+package store
+
+import (
+	"encoding/json"
+
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/config"
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/serializer"
+)
+
+const (
+	workflowSummaryPrefix = "workflow_"
+
+	// workflowSummaryExpiry is the duration in seconds after which the summary of a workflow is removed from the KV store
+	workflowSummaryExpiry = 7 * 24 * 60 * 60
+)
+
+func workflowSummaryKey(channelID, workflowID string) string {
+	return hashedKey(workflowSummaryPrefix, channelID+"_"+workflowID)
+}
+
+func GetWorkflowSummary(channelID, workflowID string) (*serializer.WorkflowSummary, error) {
+	data, appErr := config.Mattermost.KVGet(workflowSummaryKey(channelID, workflowID))
+	if appErr != nil {
+		config.Mattermost.LogError("Failed to fetch workflow summary from KV store.", "WorkflowID", workflowID, "Error", appErr.Error())
+		return nil, appErr
+	}
+
+	return serializer.WorkflowSummaryFromJSON(data)
+}
+
+// ModifyWorkflowSummary atomically updates the summary of a workflow in a channel and returns the updated summary
+func ModifyWorkflowSummary(channelID, workflowID string, modify func(summary *serializer.WorkflowSummary)) (*serializer.WorkflowSummary, error) {
+	var summary *serializer.WorkflowSummary
+	err := AtomicModifyWithExpiry(workflowSummaryKey(channelID, workflowID), workflowSummaryExpiry, func(initialBytes []byte) ([]byte, error) {
+		var err error
+		summary, err = serializer.WorkflowSummaryFromJSON(initialBytes)
+		if err != nil {
+			return nil, err
+		}
+
+		modify(summary)
+		return json.Marshal(summary)
+	})
+
+	if err != nil {
+		config.Mattermost.LogError("Failed to modify workflow summary.", "WorkflowID", workflowID, "Error", err.Error())
+		return nil, err
+	}
+
+	return summary, nil
+}
@@ -1,9 +1,14 @@
 package util
 
-import circleci2 "github.com/TomTucka/go-circleci/circleci"
+import (
+	circleci2 "github.com/TomTucka/go-circleci/circleci"
+
+	"github.com/chetanyakan/mattermost-plugin-circleci/server/config"
+)
 
 func GetCircleciClient(authToken string) *circleci2.APIClient {
 	conf := circleci2.NewConfiguration()
+	conf.BasePath = config.GetConfig().GetCircleCIAPIURL("v2")
 	conf.AddDefaultHeader("Circle-Token", authToken)
 	return circleci2.NewAPIClient(conf)
 }
@@ -57,6 +57,34 @@ func SplitArgs(s string) ([]string, error) {
 	return cleanedArgs[0:count], nil
 }
 
+// ParseNamedArgs separates the positional arguments from the named ones.
+// Named arguments are passed as `--name value` and can be repeated, or contain comma-separated values.
+func ParseNamedArgs(args []string) ([]string, map[string][]string, error) {
+	var positional []string
+	named := map[string][]string{}
+
+	for i := 0; i < len(args); i++ {
+		if !strings.HasPrefix(args[i], "--") {
+			positional = append(positional, args[i])
+			continue
+		}
+
+		name := strings.TrimPrefix(args[i], "--")
+		if name == "" || i+1 >= len(args) || strings.HasPrefix(args[i+1], "--") {
+			return nil, nil, errors.Errorf("missing value for argument `%s`", args[i])
+		}
+
+		i++
+		for _, value := range strings.Split(args[i], ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				named[name] = append(named[name], value)
+			}
+		}
+	}
+
+	return positional, named, nil
+}
+
 // SendEphemeralCommandResponse can be used to return an ephemeral message as the response for a slash command
 func SendEphemeralCommandResponse(message string) (*model.CommandResponse, *model.AppError) {
 	return &model.CommandResponse{
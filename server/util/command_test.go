@@ -0,0 +1,58 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseNamedArgs(t *testing.T) {
+	for _, tc := range []struct {
+		name               string
+		args               []string
+		expectedPositional []string
+		expectedNamed      map[string][]string
+	}{
+		{
+			name:               "positional only",
+			args:               []string{"github", "org", "repo"},
+			expectedPositional: []string{"github", "org", "repo"},
+			expectedNamed:      map[string][]string{},
+		},
+		{
+			name:               "mixed",
+			args:               []string{"github", "--branch", "main", "org", "--status", "failure", "repo"},
+			expectedPositional: []string{"github", "org", "repo"},
+			expectedNamed:      map[string][]string{"branch": {"main"}, "status": {"failure"}},
+		},
+		{
+			name:          "comma-separated values",
+			args:          []string{"--status", "failure, success,"},
+			expectedNamed: map[string][]string{"status": {"failure", "success"}},
+		},
+		{
+			name:          "repeated argument",
+			args:          []string{"--branch", "main", "--branch", "release/*"},
+			expectedNamed: map[string][]string{"branch": {"main", "release/*"}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			positional, named, err := ParseNamedArgs(tc.args)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedPositional, positional)
+			assert.Equal(t, tc.expectedNamed, named)
+		})
+	}
+}
+
+func TestParseNamedArgsMissingValue(t *testing.T) {
+	for _, args := range [][]string{
+		{"--branch"},
+		{"--branch", "--status", "failure"},
+		{"--", "value"},
+	} {
+		_, _, err := ParseNamedArgs(args)
+		assert.Error(t, err, args)
+	}
+}
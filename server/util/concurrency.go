@@ -0,0 +1,50 @@
+package util
+
+import (
+	"context"
+	"sync"
+)
+
+// RunConcurrently calls fn for each index from 0 to count-1, running at most limit calls at the same time.
+// The context passed to fn is canceled as soon as a call fails, and the first error is returned.
+func RunConcurrently(ctx context.Context, count, limit int, fn func(ctx context.Context, i int) error) error {
+	parentCtx := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	semaphore := make(chan struct{}, limit)
+
+	for i := 0; i < count && ctx.Err() == nil; i++ {
+		select {
+		case semaphore <- struct{}{}:
+		case <-ctx.Done():
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			if err := fn(ctx, i); err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
+		}(i)
+	}
+
+	wg.Wait()
+	if firstErr != nil {
+		return firstErr
+	}
+
+	// the parent context has been canceled or has reached its deadline
+	return parentCtx.Err()
+}
@@ -0,0 +1,55 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunConcurrently(t *testing.T) {
+	var running, maxRunning int32
+	results := make([]int, 20)
+
+	err := RunConcurrently(context.Background(), len(results), 3, func(ctx context.Context, i int) error {
+		current := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+
+		for {
+			max := atomic.LoadInt32(&maxRunning)
+			if current <= max || atomic.CompareAndSwapInt32(&maxRunning, max, current) {
+				break
+			}
+		}
+
+		time.Sleep(time.Millisecond)
+		results[i] = i * 2
+		return nil
+	})
+	require.NoError(t, err)
+
+	assert.LessOrEqual(t, maxRunning, int32(3))
+	for i, result := range results {
+		assert.Equal(t, i*2, result)
+	}
+}
+
+func TestRunConcurrentlyError(t *testing.T) {
+	failure := errors.New("failure")
+	var calls int32
+
+	err := RunConcurrently(context.Background(), 100, 1, func(ctx context.Context, i int) error {
+		atomic.AddInt32(&calls, 1)
+		if i == 2 {
+			return failure
+		}
+		return nil
+	})
+
+	assert.Equal(t, failure, err)
+	assert.Less(t, calls, int32(100), "no new calls should start after a failure")
+}
@@ -1,55 +1,172 @@
-// Encryption, decryption functions in this file have been picked up from
+// The legacy encryption, decryption functions in this file have been picked up from
 // https://github.com/mattermost/mattermost-plugin-jenkins/blob/18ab5d4cf441557c06bcaad42d50ef6602d0a205/server/utils.go#L37
 
 package util
 
 import (
-	"bytes"
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"io"
+	"strings"
 )
 
-func pad(src []byte) []byte {
-	padding := aes.BlockSize - len(src)%aes.BlockSize
-	padtext := bytes.Repeat([]byte{byte(padding)}, padding)
-	return append(src, padtext...)
+const (
+	// encryptionVersionV1 values are encrypted with AES-CFB and padding, without any integrity check.
+	// They are only decrypted for migrating them.
+	encryptionVersionV1 = "v1"
+	// encryptionVersionV2 values are encrypted with AES-GCM
+	encryptionVersionV2 = "v2"
+
+	versionSeparator = ":"
+)
+
+// ErrNoMatchingKey is returned when a value was not encrypted with any of the available keys
+var ErrNoMatchingKey = errors.New("the value was not encrypted with any of the available keys")
+
+// Encrypt encrypts the text with AES-GCM using a random nonce.
+// Any modification of the returned value is detected by Decrypt.
+func Encrypt(key []byte, text string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+
+	ciphertext := gcm.Seal(nonce, nonce, []byte(text), nil)
+	return base64.URLEncoding.EncodeToString(ciphertext), nil
 }
 
-func unpad(src []byte) ([]byte, error) {
-	length := len(src)
-	unpadding := int(src[length-1])
+// Decrypt decrypts a value encrypted with Encrypt.
+// An error is returned if the value has been tampered with or was encrypted with another key.
+func Decrypt(key []byte, text string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
 
-	if unpadding > length {
-		return nil, errors.New("unpad error. This could happen when incorrect encryption key is used")
+	decodedMsg, err := base64.URLEncoding.DecodeString(text)
+	if err != nil {
+		return "", err
 	}
 
-	return src[:(length - unpadding)], nil
+	if len(decodedMsg) < gcm.NonceSize() {
+		return "", errors.New("encrypted value is too short")
+	}
+
+	nonce, ciphertext := decodedMsg[:gcm.NonceSize()], decodedMsg[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", errors.New("failed to authenticate the encrypted value. It has been modified or a different key was used")
+	}
+
+	return string(plaintext), nil
 }
 
-func Encrypt(key []byte, text string) (string, error) {
+func newGCM(key []byte) (cipher.AEAD, error) {
 	block, err := aes.NewCipher(key)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	msg := pad([]byte(text))
-	ciphertext := make([]byte, aes.BlockSize+len(msg))
-	iv := ciphertext[:aes.BlockSize]
-	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
+	return cipher.NewGCM(block)
+}
+
+// KeyID returns a short identifier of the key which is stored along with the values encrypted with it.
+// It doesn't reveal the key itself.
+func KeyID(key []byte) string {
+	hash := sha256.Sum256(key)
+	return hex.EncodeToString(hash[:4])
+}
+
+// EncryptVersioned encrypts the text and prefixes it with the format version and the ID of the key,
+// so that values encrypted with a previous key or format can be recognized and migrated.
+func EncryptVersioned(key []byte, text string) (string, error) {
+	ciphertext, err := Encrypt(key, text)
+	if err != nil {
 		return "", err
 	}
 
-	cfb := cipher.NewCFBEncrypter(block, iv)
-	cfb.XORKeyStream(ciphertext[aes.BlockSize:], msg)
-	finalMsg := base64.URLEncoding.EncodeToString(ciphertext)
-	return finalMsg, nil
+	return strings.Join([]string{encryptionVersionV2, KeyID(key), ciphertext}, versionSeparator), nil
 }
 
-func Decrypt(key []byte, text string) (string, error) {
+// DecryptVersioned decrypts a value encrypted with EncryptVersioned or with one of the legacy formats,
+// using the matching key from the list. The index of the key used is returned along with the text.
+func DecryptVersioned(keys [][]byte, text string) (string, int, error) {
+	parts := strings.SplitN(text, versionSeparator, 3)
+	if len(parts) == 3 && (parts[0] == encryptionVersionV2 || parts[0] == encryptionVersionV1) {
+		decrypt := Decrypt
+		if parts[0] == encryptionVersionV1 {
+			decrypt = decryptLegacy
+		}
+
+		for i, key := range keys {
+			if KeyID(key) != parts[1] {
+				continue
+			}
+
+			plaintext, err := decrypt(key, parts[2])
+			if err != nil {
+				return "", -1, err
+			}
+
+			return plaintext, i, nil
+		}
+
+		return "", -1, ErrNoMatchingKey
+	}
+
+	// Unversioned values don't record their key, so each key is tried in order.
+	// A wrong key usually produces garbage instead of an error, which is detected by checking the text is printable.
+	for i, key := range keys {
+		plaintext, err := decryptLegacy(key, text)
+		if err == nil && isPrintable(plaintext) {
+			return plaintext, i, nil
+		}
+	}
+
+	return "", -1, ErrNoMatchingKey
+}
+
+// IsCurrentVersion checks if the value was encrypted in the format currently used by EncryptVersioned
+func IsCurrentVersion(text string) bool {
+	return strings.HasPrefix(text, encryptionVersionV2+versionSeparator)
+}
+
+func isPrintable(text string) bool {
+	for _, r := range text {
+		if r < 0x20 || r > 0x7e {
+			return false
+		}
+	}
+
+	return true
+}
+
+func unpad(src []byte) ([]byte, error) {
+	length := len(src)
+	if length == 0 {
+		return nil, errors.New("unpad error. The decrypted value is empty")
+	}
+
+	unpadding := int(src[length-1])
+
+	if unpadding > length {
+		return nil, errors.New("unpad error. This could happen when incorrect encryption key is used")
+	}
+
+	return src[:(length - unpadding)], nil
+}
+
+func decryptLegacy(key []byte, text string) (string, error) {
 	block, err := aes.NewCipher(key)
 	if err != nil {
 		return "", err
@@ -60,7 +177,7 @@ func Decrypt(key []byte, text string) (string, error) {
 		return "", err
 	}
 
-	if (len(decodedMsg) % aes.BlockSize) != 0 {
+	if len(decodedMsg) < aes.BlockSize || (len(decodedMsg)%aes.BlockSize) != 0 {
 		return "", errors.New("blocksize must be a multiple of decoded message length")
 	}
 
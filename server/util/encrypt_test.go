@@ -0,0 +1,159 @@
+package util
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testKey      = []byte("0123456789abcdef0123456789abcdef")
+	testOtherKey = []byte("fedcba9876543210fedcba9876543210")
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	encrypted, err := Encrypt(testKey, "secret-token")
+	require.NoError(t, err)
+	assert.NotContains(t, encrypted, "secret-token")
+
+	decrypted, err := Decrypt(testKey, encrypted)
+	require.NoError(t, err)
+	assert.Equal(t, "secret-token", decrypted)
+
+	other, err := Encrypt(testKey, "secret-token")
+	require.NoError(t, err)
+	assert.NotEqual(t, encrypted, other, "a random nonce must be used for each value")
+}
+
+func TestDecryptDetectsTampering(t *testing.T) {
+	encrypted, err := Encrypt(testKey, "secret-token")
+	require.NoError(t, err)
+
+	decoded, err := base64.URLEncoding.DecodeString(encrypted)
+	require.NoError(t, err)
+
+	for i := range decoded {
+		tampered := make([]byte, len(decoded))
+		copy(tampered, decoded)
+		tampered[i] ^= 0x01
+
+		_, err := Decrypt(testKey, base64.URLEncoding.EncodeToString(tampered))
+		assert.Error(t, err, "modifying byte %d must be detected", i)
+	}
+
+	_, err = Decrypt(testKey, base64.URLEncoding.EncodeToString(decoded[:len(decoded)-1]))
+	assert.Error(t, err, "truncated values must be detected")
+
+	_, err = Decrypt(testKey, "")
+	assert.Error(t, err)
+}
+
+func TestDecryptWithWrongKey(t *testing.T) {
+	encrypted, err := Encrypt(testKey, "secret-token")
+	require.NoError(t, err)
+
+	_, err = Decrypt(testOtherKey, encrypted)
+	assert.Error(t, err)
+}
+
+func TestDecryptVersioned(t *testing.T) {
+	t.Run("current key", func(t *testing.T) {
+		encrypted, err := EncryptVersioned(testKey, "secret-token")
+		require.NoError(t, err)
+		assert.True(t, IsCurrentVersion(encrypted))
+
+		decrypted, keyIndex, err := DecryptVersioned([][]byte{testKey, testOtherKey}, encrypted)
+		require.NoError(t, err)
+		assert.Equal(t, "secret-token", decrypted)
+		assert.Equal(t, 0, keyIndex)
+	})
+
+	t.Run("previous key", func(t *testing.T) {
+		encrypted, err := EncryptVersioned(testOtherKey, "secret-token")
+		require.NoError(t, err)
+
+		decrypted, keyIndex, err := DecryptVersioned([][]byte{testKey, testOtherKey}, encrypted)
+		require.NoError(t, err)
+		assert.Equal(t, "secret-token", decrypted)
+		assert.Equal(t, 1, keyIndex)
+	})
+
+	t.Run("wrong key", func(t *testing.T) {
+		encrypted, err := EncryptVersioned(testOtherKey, "secret-token")
+		require.NoError(t, err)
+
+		_, _, err = DecryptVersioned([][]byte{testKey}, encrypted)
+		assert.Equal(t, ErrNoMatchingKey, err)
+	})
+
+	t.Run("tampered value", func(t *testing.T) {
+		encrypted, err := EncryptVersioned(testKey, "secret-token")
+		require.NoError(t, err)
+
+		parts := strings.SplitN(encrypted, versionSeparator, 3)
+		decoded, err := base64.URLEncoding.DecodeString(parts[2])
+		require.NoError(t, err)
+		decoded[len(decoded)-1] ^= 0x01
+		parts[2] = base64.URLEncoding.EncodeToString(decoded)
+
+		_, _, err = DecryptVersioned([][]byte{testKey}, strings.Join(parts, versionSeparator))
+		assert.Error(t, err)
+	})
+
+	t.Run("legacy versioned value", func(t *testing.T) {
+		encrypted, err := encryptLegacy(testKey, "secret-token")
+		require.NoError(t, err)
+		encrypted = strings.Join([]string{encryptionVersionV1, KeyID(testKey), encrypted}, versionSeparator)
+		assert.False(t, IsCurrentVersion(encrypted))
+
+		decrypted, keyIndex, err := DecryptVersioned([][]byte{testKey}, encrypted)
+		require.NoError(t, err)
+		assert.Equal(t, "secret-token", decrypted)
+		assert.Equal(t, 0, keyIndex)
+	})
+
+	t.Run("legacy unversioned value", func(t *testing.T) {
+		encrypted, err := encryptLegacy(testOtherKey, "secret-token")
+		require.NoError(t, err)
+		assert.False(t, IsCurrentVersion(encrypted))
+
+		decrypted, keyIndex, err := DecryptVersioned([][]byte{testKey, testOtherKey}, encrypted)
+		require.NoError(t, err)
+		assert.Equal(t, "secret-token", decrypted)
+		assert.Equal(t, 1, keyIndex)
+	})
+}
+
+// encryptLegacy encrypts the text with AES-CFB. It is used for testing the migration of legacy values.
+func encryptLegacy(key []byte, text string) (string, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+
+	msg := pad([]byte(text))
+	ciphertext := make([]byte, aes.BlockSize+len(msg))
+	iv := ciphertext[:aes.BlockSize]
+	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
+		return "", err
+	}
+
+	cfb := cipher.NewCFBEncrypter(block, iv)
+	cfb.XORKeyStream(ciphertext[aes.BlockSize:], msg)
+	finalMsg := base64.URLEncoding.EncodeToString(ciphertext)
+	return finalMsg, nil
+}
+
+func pad(src []byte) []byte {
+	padding := aes.BlockSize - len(src)%aes.BlockSize
+	padtext := bytes.Repeat([]byte{byte(padding)}, padding)
+	return append(src, padtext...)
+}
@@ -0,0 +1,42 @@
+package util
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const signatureVersionPrefix = "v1="
+
+// VerifyWebhookSignature checks the HMAC-SHA256 signature of the payload sent in the `circleci-signature` header.
+// The header can contain multiple comma-separated signatures of the form `v1=<hex digest>`.
+func VerifyWebhookSignature(secret, header string, body []byte) error {
+	if secret == "" {
+		return errors.New("no webhook secret has been generated for the project")
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = mac.Write(body)
+	expected := mac.Sum(nil)
+
+	for _, signature := range strings.Split(header, ",") {
+		signature = strings.TrimSpace(signature)
+		if !strings.HasPrefix(signature, signatureVersionPrefix) {
+			continue
+		}
+
+		got, err := hex.DecodeString(strings.TrimPrefix(signature, signatureVersionPrefix))
+		if err != nil {
+			continue
+		}
+
+		if hmac.Equal(got, expected) {
+			return nil
+		}
+	}
+
+	return errors.New("webhook signature did not match")
+}
@@ -0,0 +1,48 @@
+package util
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = mac.Write(body)
+	return "v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifyWebhookSignature(t *testing.T) {
+	body := []byte(`{"type":"job-completed"}`)
+	signature := sign("secret", body)
+
+	for _, tc := range []struct {
+		name    string
+		secret  string
+		header  string
+		body    []byte
+		isValid bool
+	}{
+		{name: "valid", secret: "secret", header: signature, body: body, isValid: true},
+		{name: "multiple signatures", secret: "secret", header: sign("old-secret", body) + ", " + signature, body: body, isValid: true},
+		{name: "unknown version", secret: "secret", header: "v0=abc," + signature, body: body, isValid: true},
+		{name: "tampered body", secret: "secret", header: signature, body: []byte(`{"type":"workflow-completed"}`)},
+		{name: "wrong secret", secret: "other-secret", header: signature, body: body},
+		{name: "missing header", secret: "secret", header: "", body: body},
+		{name: "invalid hex", secret: "secret", header: "v1=zz", body: body},
+		{name: "missing version", secret: "secret", header: signature[len("v1="):], body: body},
+		{name: "no secret", secret: "", header: sign("", body), body: body},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := VerifyWebhookSignature(tc.secret, tc.header, tc.body)
+			if tc.isValid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}